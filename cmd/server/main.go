@@ -112,6 +112,7 @@ func setupHTTPServer(service *api.Service, cfg config.AppConfig) *http.Server {
 	mux.HandleFunc("/", handleRoot)
 	mux.HandleFunc("/compress", service.HandleCompress)
 	mux.HandleFunc("/batch-compress", service.HandleBatchCompress)
+	mux.HandleFunc("/capabilities", service.HandleCapabilities)
 	
 	// Add Prometheus metrics endpoint if enabled
 	if cfg.Metrics.PrometheusEnabled {
@@ -9,6 +9,7 @@ require golang.org/x/image v0.18.0
 require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/chai2010/webp v1.1.1
+	github.com/davidbyttow/govips/v2 v2.16.0
 	github.com/prometheus/client_golang v1.22.0
 	github.com/shirou/gopsutil/v4 v4.25.3
 	google.golang.org/grpc v1.71.1
@@ -12,6 +12,9 @@ import (
 	"time"
 	"strings"
 	"sync"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
 )
 
 // BatchRequest represents a single image compression request in a batch
@@ -19,7 +22,7 @@ type BatchRequest struct {
 	Filename  string
 	Data      io.Reader
 	Format    string
-	Quality   int
+	Params    compression.CompressionParams
 	Algorithm string
 }
 
@@ -35,6 +38,17 @@ type BatchProcessError struct {
 	Error    error
 }
 
+// Overloaded reports whether any item was shed by the worker pool. A batch
+// with shed items is incomplete, so callers are told to retry the whole batch.
+func (b BatchResponse) Overloaded() bool {
+	for _, procErr := range b.ProcessingErrors {
+		if errors.Is(procErr.Error, worker.ErrOverloaded) {
+			return true
+		}
+	}
+	return false
+}
+
 // ProcessBatchRequests processes multiple image compression requests concurrently
 // This is the exported method that both HTTP and gRPC handlers will use
 func (s *Service) ProcessBatchRequests(
@@ -51,6 +65,11 @@ func (s *Service) ProcessBatchRequests(
 		sem = make(chan struct{}, s.workerPool.TotalWorkerCount())
 	)
 
+	// Batch items queue behind interactive requests unless the caller says otherwise
+	if _, ok := worker.PriorityFromContext(ctx); !ok {
+		ctx = worker.WithPriority(ctx, worker.PriorityBatch)
+	}
+
 	for _, req := range requests {
 		wg.Add(1)
 
@@ -82,7 +101,7 @@ func (s *Service) ProcessBatchRequests(
 				request.Filename,
 				request.Data,
 				request.Format,
-				request.Quality,
+				request.Params,
 				request.Algorithm,
 			)
 
@@ -116,7 +135,7 @@ func (s *Service) ProcessBatchRequests(
 func ConvertFilesToBatchRequests(
 	files []*multipart.FileHeader,
 	format string,
-	quality int,
+	params compression.CompressionParams,
 	algorithm string,
 ) ([]BatchRequest, error) {
 	requests := make([]BatchRequest, 0, len(files))
@@ -133,16 +152,53 @@ func ConvertFilesToBatchRequests(
 			return nil, fmt.Errorf("reading file %s: %w", fileHeader.Filename, err)
 		}
 
+		requests = append(requests, NewBatchRequests(fileHeader.Filename, fileBytes, format, params, algorithm)...)
+	}
+
+	return requests, nil
+}
+
+// NewBatchRequests builds the batch requests for a single input file.
+// Multi-page TIFFs get one request per page so each page becomes its own
+// output, named like photo_page0.tif with pages counting from 0 as in the
+// page parameter, which batches otherwise ignore.
+func NewBatchRequests(
+	filename string,
+	data []byte,
+	format string,
+	params compression.CompressionParams,
+	algorithm string,
+) []BatchRequest {
+	params.Page = 0
+	pages := compression.TIFFPageCount(data)
+	if pages <= 1 {
+		return []BatchRequest{{
+			Filename:  filename,
+			Data:      bytes.NewReader(data),
+			Format:    format,
+			Params:    params,
+			Algorithm: algorithm,
+		}}
+	}
+
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+
+	requests := make([]BatchRequest, 0, pages)
+	for page := 0; page < pages; page++ {
+		pageParams := params
+		pageParams.Page = page
+
 		requests = append(requests, BatchRequest{
-			Filename:  fileHeader.Filename,
-			Data:      bytes.NewReader(fileBytes),
+			Filename:  fmt.Sprintf("%s_page%d%s", base, page, ext),
+			Data:      bytes.NewReader(data),
 			Format:    format,
-			Quality:   quality,
+			Params:    pageParams,
 			Algorithm: algorithm,
 		})
 	}
 
-	return requests, nil
+	return requests
 }
 
 // CreateZipFromResults creates a zip file from compression results
@@ -166,7 +222,10 @@ func CreateZipFromResults(results []CompressionResult) ([]byte, error) {
 
 		// Generate a unique filename for the zip entry
 		baseName := strings.TrimSuffix(filepath.Base(result.Filename), filepath.Ext(result.Filename))
-		ext := result.Format
+		ext := result.Extension
+		if ext == "" {
+			ext = result.Format
+		}
 
 		count := nameCounts[baseName]
 		nameCounts[baseName]++
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+)
+
+// FormatCapability describes one registered output format
+type FormatCapability struct {
+	Name      string   `json:"name"`
+	MIMEType  string   `json:"mime_type"`
+	Extension string   `json:"extension"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Options   []string `json:"options"`
+}
+
+// Capabilities lists the output formats, format modes and algorithms the service accepts
+type Capabilities struct {
+	Formats          []FormatCapability `json:"formats"`
+	Modes            []string           `json:"modes"`
+	DefaultFormat    string             `json:"default_format"`
+	Algorithms       []string           `json:"algorithms"`
+	DefaultAlgorithm string             `json:"default_algorithm"`
+}
+
+// Capabilities builds the capability listing from the processor's encoder and algorithm registries
+func (s *Service) Capabilities() Capabilities {
+	capabilities := Capabilities{
+		Modes:            []string{compression.FormatAuto, compression.FormatBest},
+		DefaultFormat:    s.defaultFormat,
+		Algorithms:       s.processor.Algorithms(),
+		DefaultAlgorithm: s.defaultAlgorithm,
+	}
+
+	for _, encoder := range s.processor.Encoders() {
+		options := encoder.Options()
+		if options == nil {
+			options = []string{}
+		}
+		capabilities.Formats = append(capabilities.Formats, FormatCapability{
+			Name:      encoder.Name(),
+			MIMEType:  encoder.MIMEType(),
+			Extension: encoder.Extension(),
+			Aliases:   s.processor.FormatAliases(encoder.Name()),
+			Options:   options,
+		})
+	}
+
+	return capabilities
+}
+
+// HandleCapabilities reports the supported output formats as JSON
+func (s *Service) HandleCapabilities(w http.ResponseWriter, r *http.Request) {
+	status := "success"
+	defer func() {
+		metrics.GetRequestCounter().WithLabelValues("capabilities", status).Inc()
+	}()
+
+	if r.Method != http.MethodGet {
+		status = "method_not_allowed"
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Capabilities()); err != nil {
+		log.Printf("Error writing capabilities response: %v", err)
+	}
+}
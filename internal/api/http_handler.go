@@ -3,14 +3,18 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"path/filepath"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
 )
 
 // HandleCompress handles single image compression requests via HTTP
@@ -50,7 +54,16 @@ func (s *Service) HandleCompress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse parameters
-	quality, format, algorithm := s.parseParameters(r)
+	params, format, algorithm, err := s.parseParameters(r)
+	if err == nil {
+		ctx, err = s.withScheduling(ctx, r)
+	}
+	if err != nil {
+		status = "bad_request"
+		http.Error(w, "Invalid parameters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	format = negotiateAuto(w, r, format)
 
 	// Process the image using the core CompressImage method
 	result, err := s.CompressImage(
@@ -58,31 +71,56 @@ func (s *Service) HandleCompress(w http.ResponseWriter, r *http.Request) {
 		header.Filename,
 		bytes.NewReader(fileBytes),
 		format,
-		quality,
+		params,
 		algorithm,
 	)
 
+	if errors.Is(err, compression.ErrImageTooLarge) {
+		status = "image_too_large"
+		http.Error(w, "Error compressing image: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if errors.Is(err, worker.ErrOverloaded) {
+		status = "overloaded"
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.RetryAfter().Seconds())))
+		http.Error(w, "Service overloaded: "+err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, compression.ErrBudgetUnreachable) {
+		status = "budget_unreachable"
+		http.Error(w, "Error compressing image: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		status = "compression_error"
 		http.Error(w, "Error compressing image: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Record metrics
+	// Record metrics against the format actually produced
+	format = result.Format
 	metrics.RecordCompressionRatio(
 		format,
 		result.AlgorithmUsed,
+		result.Kernel,
 		result.OriginalSize,
 		result.CompressedSize,
 	)
 
 	// Set response headers
-	w.Header().Set("Content-Type", fmt.Sprintf("image/%s", format))
+	w.Header().Set("Content-Type", result.MIMEType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf(
 		"attachment; filename=%s.%s",
 		filepath.Base(header.Filename),
-		format,
+		result.Extension,
 	))
+	w.Header().Set("X-Quality-Used", strconv.Itoa(result.QualityUsed))
+	w.Header().Set("X-Encode-Passes", strconv.Itoa(result.EncodePasses))
+	w.Header().Set("X-Color-Converted", strconv.FormatBool(result.ColorConverted))
+	if result.SSIM > 0 {
+		w.Header().Set("X-SSIM", strconv.FormatFloat(result.SSIM, 'f', 4, 64))
+		w.Header().Set("X-PSNR", strconv.FormatFloat(result.PSNR, 'f', 2, 64))
+	}
 
 	// Send the response
 	_, err = w.Write(result.Data)
@@ -135,18 +173,42 @@ func (s *Service) HandleBatchCompress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse parameters
-	quality, format, algorithm := s.parseParameters(r)
+	params, format, algorithm, err := s.parseParameters(r)
+	if err == nil {
+		ctx, err = s.withScheduling(ctx, r)
+	}
+	if err != nil {
+		status = "bad_request"
+		http.Error(w, "Invalid parameters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	format = negotiateAuto(w, r, format)
 
 	// Convert multipart files to batch requests
-	requests, err := ConvertFilesToBatchRequests(files, format, quality, algorithm)
+	requests, err := ConvertFilesToBatchRequests(files, format, params, algorithm)
 	if err != nil {
 		status = "batch_preparation_failed"
 		http.Error(w, "Error preparing batch: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Multi-page TIFFs expand to a request per page, which counts against the limit too
+	if err := s.CheckBatchSize(len(requests)); err != nil {
+		status = "bad_request"
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Process the batch of images using the unified processor
 	batchResponse := s.ProcessBatchRequests(ctx, requests)
+
+	// Don't send a zip with shed images silently missing
+	if batchResponse.Overloaded() {
+		status = "overloaded"
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.RetryAfter().Seconds())))
+		http.Error(w, "Service overloaded: "+worker.ErrOverloaded.Error(), http.StatusTooManyRequests)
+		return
+	}
 	
 	// If all files failed, return an error
 	if len(batchResponse.Results) == 0 && len(batchResponse.ProcessingErrors) > 0 {
@@ -169,6 +231,7 @@ func (s *Service) HandleBatchCompress(w http.ResponseWriter, r *http.Request) {
 		metrics.RecordCompressionRatio(
 			result.Format,
 			result.AlgorithmUsed,
+			result.Kernel,
 			result.OriginalSize,
 			result.CompressedSize,
 		)
@@ -188,4 +251,35 @@ func (s *Service) HandleBatchCompress(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Error processing %s: %v", procErr.Filename, procErr.Error)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// withScheduling schedules the request's jobs for the tenant named by the
+// tenant header and at the priority named by the priority parameter, if any
+func (s *Service) withScheduling(ctx context.Context, r *http.Request) (context.Context, error) {
+	if s.tenantHeader != "" {
+		if tenant := r.Header.Get(s.tenantHeader); tenant != "" {
+			ctx = worker.WithTenant(ctx, tenant)
+		}
+	}
+
+	name := r.FormValue("priority")
+	if name == "" {
+		return ctx, nil
+	}
+	priority, err := worker.ParsePriority(name)
+	if err != nil {
+		return ctx, err
+	}
+	return worker.WithPriority(ctx, priority), nil
+}
+
+// negotiateAuto resolves format=auto, whether requested or the configured
+// default, against the Accept header. The response then depends on Accept, so
+// caches are told to key on it.
+func negotiateAuto(w http.ResponseWriter, r *http.Request, format string) string {
+	if format != compression.FormatAuto {
+		return format
+	}
+	w.Header().Set("Vary", "Accept")
+	return negotiateFormat(r.Header.Get("Accept"))
+}
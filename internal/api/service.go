@@ -5,12 +5,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image/color"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/jpegenc"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/metadata"
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/config"
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
 )
@@ -19,6 +25,8 @@ import (
 var (
 	ErrInvalidResultType = errors.New("invalid result type")
 	ErrProcessingTimeout = errors.New("processing timeout")
+	ErrUnsupportedFormat = errors.New("unsupported output format")
+	ErrBatchTooLarge     = errors.New("batch too large")
 )
 
 // CompressionResult represents the result of a compression operation
@@ -32,6 +40,14 @@ type CompressionResult struct {
 	AlgorithmUsed    string
 	Filename         string  // Added to store the original filename
 	Format           string  // Added to store the output format
+	MIMEType         string  // Content-Type of the output format
+	Extension        string  // File extension of the output format, without the dot
+	QualityUsed      int     // Encoder quality picked for the output
+	EncodePasses     int     // Number of encodes needed to reach the output
+	SSIM             float64 // Achieved structural similarity, 0 if not measured
+	PSNR             float64 // Achieved PSNR in dB, 0 if not measured
+	Kernel           string  // Resampling kernel used for any resize
+	ColorConverted   bool    // Source was converted from its ICC profile to sRGB
 }
 
 // Service handles the API endpoints for image compression
@@ -45,19 +61,25 @@ type Service struct {
 	batchProcessingTimeout time.Duration
 	maxUploadSize          int64
 	maxBatchSize           int
+	tenantHeader           string
 }
 
 // NewServiceWithConfig creates a new service with the given configuration
 func NewServiceWithConfig(config config.ServiceConfig) *Service {
 	// Create worker pool
 	workerPool := worker.NewPool(config.WorkerCount, config.JobQueueSize, config.EnableMetrics)
+	workerPool.SetMaxWait(config.MaxQueueWait)
+	workerPool.SetMemoryBudget(config.MemoryBudget)
+	workerPool.SetTenants(tenantLimits(config.Tenants), tenantLimit(config.DefaultTenant))
 
-	// Create image processor
+	// Create image processor, fanning best-format candidates out on the same pool
 	processor := compression.NewImageProcessor()
+	processor.SetPool(workerPool)
+	processor.SetMaxPixels(config.MaxPixels)
 
-	// Set default algorithm if specified
-	if config.DefaultAlgorithm != "scale" {
-		processor.SetDefaultAlgorithm(config.DefaultAlgorithm)
+	// Set default algorithm if specified, keeping the built-in one if it isn't registered
+	if config.DefaultAlgorithm != "scale" && !processor.SetDefaultAlgorithm(config.DefaultAlgorithm) {
+		log.Printf("Unknown default algorithm %q, using %q", config.DefaultAlgorithm, processor.GetDefaultAlgorithm().Name())
 	}
 
 	// Start resource monitoring if metrics are enabled
@@ -70,14 +92,46 @@ func NewServiceWithConfig(config config.ServiceConfig) *Service {
 		processor:              processor,
 		defaultQuality:         config.DefaultQuality,
 		defaultFormat:          config.DefaultFormat,
-		defaultAlgorithm:       config.DefaultAlgorithm,
+		defaultAlgorithm:       processor.GetDefaultAlgorithm().Name(),
 		imageProcessingTimeout: config.ImageProcessingTimeout,
 		batchProcessingTimeout: config.BatchProcessingTimeout,
 		maxUploadSize:          config.MaxUploadSize,
 		maxBatchSize:           config.MaxBatchSize,
+		tenantHeader:           config.TenantHeader,
 	}
 }
 
+// tenantLimits converts configured tenants to worker pool limits
+func tenantLimits(tenants map[string]config.TenantConfig) map[string]worker.TenantLimits {
+	limits := make(map[string]worker.TenantLimits, len(tenants))
+	for name, tenant := range tenants {
+		limits[name] = tenantLimit(tenant)
+	}
+	return limits
+}
+
+func tenantLimit(tenant config.TenantConfig) worker.TenantLimits {
+	return worker.TenantLimits{
+		Weight:      tenant.Weight,
+		MaxInFlight: tenant.MaxInFlight,
+		MaxQueued:   tenant.MaxQueued,
+	}
+}
+
+// CheckBatchSize checks a batch, counted after multi-page TIFFs are expanded
+// into one request per page, is within the maximum batch size
+func (s *Service) CheckBatchSize(requests int) error {
+	if requests > s.maxBatchSize {
+		return fmt.Errorf("%w: %d images or pages, maximum is %d", ErrBatchTooLarge, requests, s.maxBatchSize)
+	}
+	return nil
+}
+
+// TenantHeader returns the request header, or gRPC metadata key, that names the tenant
+func (s *Service) TenantHeader() string {
+	return s.tenantHeader
+}
+
 
 
 // CompressImage processes an image directly and returns the result
@@ -87,7 +141,7 @@ func (s *Service) CompressImage(
 	filename string,
 	input io.Reader,
 	format string,
-	quality int,
+	params compression.CompressionParams,
 	algorithm string,
 ) (CompressionResult, error) {
 	// Create a new context with a timeout if not already set
@@ -97,6 +151,11 @@ func (s *Service) CompressImage(
 		defer cancel()
 	}
 
+	// Reject unknown algorithms up front instead of encoding with the default
+	if err := s.ValidateAlgorithm(algorithm); err != nil {
+		return CompressionResult{Error: err}, err
+	}
+
 	// Read the input data
 	inputData, err := io.ReadAll(input)
 	if err != nil {
@@ -108,7 +167,7 @@ func (s *Service) CompressImage(
 		filename,
 		bytes.NewReader(inputData),
 		format,
-		quality,
+		params,
 		algorithm,
 		s.processor,
 	)
@@ -120,8 +179,8 @@ func (s *Service) CompressImage(
 	// Start time measurement
 	startTime := time.Now()
 	
-	// Submit job to worker pool
-	if err := s.workerPool.Submit(job, resultChan, errChan); err != nil {
+	// Submit job to worker pool, shedding it if the pool is overloaded
+	if err := s.workerPool.Admit(ctx, job, resultChan, errChan); err != nil {
 		return CompressionResult{Error: fmt.Errorf("submitting job: %w", err)}, err
 	}
 
@@ -134,6 +193,13 @@ func (s *Service) CompressImage(
 			return CompressionResult{Error: ErrInvalidResultType}, ErrInvalidResultType
 		}
 		
+		// Describe the output with its encoder
+		outputFormat := compressionResult.Format()
+		mimeType, extension := "application/octet-stream", outputFormat
+		if encoder, ok := s.processor.GetEncoder(outputFormat); ok {
+			mimeType, extension = encoder.MIMEType(), encoder.Extension()
+		}
+
 		// Return the result with the new fields
 		return CompressionResult{
 			Data:             compressionResult.Data(),
@@ -144,7 +210,15 @@ func (s *Service) CompressImage(
 			CompressionRatio: compressionResult.CompressionRatio(),
 			AlgorithmUsed:    compressionResult.AlgorithmUsed(),
 			Filename:         filename,
-			Format:           format,
+			Format:           outputFormat,
+			MIMEType:         mimeType,
+			Extension:        extension,
+			QualityUsed:      compressionResult.QualityUsed(),
+			EncodePasses:     compressionResult.EncodePasses(),
+			SSIM:             compressionResult.SSIM(),
+			PSNR:             compressionResult.PSNR(),
+			Kernel:           params.Kernel.Name(),
+			ColorConverted:   compressionResult.ColorConverted(),
 		}, nil
 		
 	case err := <-errChan:
@@ -155,21 +229,206 @@ func (s *Service) CompressImage(
 	}
 }
 
-// parseParameters parses and validates request parameters
-func (s *Service) parseParameters(r *http.Request) (int, string, string) {
+// ValidateParams checks compression settings that arrive already parsed, as
+// over gRPC, against the same limits parseParameters applies to form values.
+// A quality of 0 means unset and becomes the default quality.
+func (s *Service) ValidateParams(params *compression.CompressionParams) error {
+	if params.Quality == 0 {
+		params.Quality = s.defaultQuality
+	}
+	if err := checkQuality(params.Quality); err != nil {
+		return err
+	}
+	if err := checkDimension(params.Width); err != nil {
+		return fmt.Errorf("width: %w", err)
+	}
+	if err := checkDimension(params.Height); err != nil {
+		return fmt.Errorf("height: %w", err)
+	}
+	if err := checkScale(params.Scale); err != nil {
+		return err
+	}
+	if err := checkPage(params.Page); err != nil {
+		return err
+	}
+	if err := checkMaxBytes(params.MaxBytes); err != nil {
+		return err
+	}
+	if err := checkTargetSSIM(params.TargetSSIM); err != nil {
+		return err
+	}
+	if err := checkColors(params.Colors); err != nil {
+		return err
+	}
+	if params.NearLosslessStrength < 0 || params.NearLosslessStrength > compression.MaxNearLossless {
+		return fmt.Errorf("near_lossless must be between 0 and %d", compression.MaxNearLossless)
+	}
+	if params.Exact && !params.Lossless && params.NearLosslessStrength == 0 {
+		return fmt.Errorf("exact requires lossless or near_lossless")
+	}
+	return nil
+}
+
+// parseParameters parses and validates request parameters.
+// Malformed, out of range or unknown values are errors rather than defaults.
+// Numeric settings are checked against the same limits ValidateParams
+// applies over gRPC.
+func (s *Service) parseParameters(r *http.Request) (compression.CompressionParams, string, string, error) {
 	// Parse quality
 	quality, err := validateQuality(r.FormValue("quality"), s.defaultQuality)
 	if err != nil {
-		quality = s.defaultQuality
+		return compression.CompressionParams{}, "", "", fmt.Errorf("quality: %w", err)
 	}
 
-	// Parse format
-	format := validateFormat(r.FormValue("format"), s.defaultFormat)
+	// Parse byte budget
+	maxBytes, err := validateMaxBytes(r.FormValue("max_bytes"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("max_bytes: %w", err)
+	}
+
+	// Parse perceptual target
+	targetSSIM, err := validateTargetSSIM(r.FormValue("target_ssim"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("target_ssim: %w", err)
+	}
+
+	// Parse resize, independent of the encoder quality
+	width, err := validateDimension(r.FormValue("width"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("width: %w", err)
+	}
+	height, err := validateDimension(r.FormValue("height"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("height: %w", err)
+	}
+	scale, err := validateScale(r.FormValue("scale"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("scale: %w", err)
+	}
+
+	// Parse the TIFF page
+	page, err := validatePage(r.FormValue("page"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("page: %w", err)
+	}
+
+	// Parse fit mode, gravity and letterbox background
+	fit, err := compression.ParseFitMode(r.FormValue("fit"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("fit: %w", err)
+	}
+	gravity, err := compression.ParseGravity(r.FormValue("gravity"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("gravity: %w", err)
+	}
+	background, err := validateBackground(r.FormValue("background"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("background: %w", err)
+	}
+
+	// Parse resampling kernel
+	kernel, err := compression.ParseKernel(r.FormValue("kernel"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("kernel: %w", err)
+	}
+
+	// Parse metadata policy, stripping everything unless asked otherwise. A
+	// misspelled policy is an error rather than a strip nobody asked for
+	metadataPolicy, err := metadata.ParsePolicy(r.FormValue("metadata"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("metadata: %w", err)
+	}
+
+	// Parse EXIF auto-orientation, on unless explicitly disabled
+	autoOrient, err := validateBool(r.FormValue("auto_orient"), true)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("auto_orient: %w", err)
+	}
+
+	// Parse lossless WebP options
+	lossless, err := validateBool(r.FormValue("lossless"), false)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("lossless: %w", err)
+	}
+	nearLossless, err := validateNearLossless(r.FormValue("near_lossless"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", err
+	}
+	exact, err := validateExact(r.FormValue("exact"), lossless || nearLossless > 0)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", err
+	}
+
+	// Parse PNG zlib effort and palette quantization
+	pngCompression, err := compression.ParsePNGCompression(r.FormValue("png_compression"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("png_compression: %w", err)
+	}
+	colors, err := validateColors(r.FormValue("colors"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("colors: %w", err)
+	}
+	dither, err := validateBool(r.FormValue("dither"), true)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("dither: %w", err)
+	}
+
+	// Parse JPEG scan, subsampling and entropy coding options
+	progressive, err := validateBool(r.FormValue("progressive"), false)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("progressive: %w", err)
+	}
+	subsampling, err := jpegenc.ParseSubsampling(r.FormValue("subsampling"))
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("subsampling: %w", err)
+	}
+	optimizeHuffman, err := validateBool(r.FormValue("optimize_huffman"), false)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", fmt.Errorf("optimize_huffman: %w", err)
+	}
+
+	// Parse format; auto is left for the handler to negotiate with the Accept header
+	format, err := validateFormat(r.FormValue("format"), s.defaultFormat, s.processor)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", err
+	}
 
 	// Parse algorithm
-	algorithm := validateAlgorithm(r.FormValue("algorithm"), s.defaultAlgorithm)
+	algorithm, err := validateAlgorithm(r.FormValue("algorithm"), s.defaultAlgorithm, s.processor)
+	if err != nil {
+		return compression.CompressionParams{}, "", "", err
+	}
 
-	return quality, format, algorithm
+	params := compression.CompressionParams{
+		Quality:    quality,
+		Width:      width,
+		Height:     height,
+		Scale:      scale,
+		Fit:        fit,
+		Gravity:    gravity,
+		Background: background,
+		Kernel:     kernel,
+		MaxBytes:   maxBytes,
+		TargetSSIM: targetSSIM,
+
+		DisableAutoOrient: !autoOrient,
+		Metadata:          metadataPolicy,
+		Page:              page,
+
+		Lossless:             lossless,
+		NearLosslessStrength: nearLossless,
+		Exact:                exact,
+
+		PNGCompression: pngCompression,
+		Colors:         colors,
+		DisableDither:  !dither,
+
+		Progressive:       progressive,
+		ChromaSubsampling: subsampling,
+		OptimizeHuffman:   optimizeHuffman,
+	}
+
+	return params, format, algorithm, nil
 }
 
 // validateQuality validates the quality parameter
@@ -183,51 +442,314 @@ func validateQuality(qualityStr string, defaultQuality int) (int, error) {
 		return defaultQuality, err
 	}
 
-	if quality < 1 || quality > 100 {
-		return defaultQuality, fmt.Errorf("quality must be between 1 and 100")
+	if err := checkQuality(quality); err != nil {
+		return defaultQuality, err
 	}
 
 	return quality, nil
 }
 
-// validateFormat validates the format parameter
-func validateFormat(format, defaultFormat string) string {
+// checkQuality checks a parsed quality is in range
+func checkQuality(quality int) error {
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// validateDimension validates a width or height parameter, 0 means keep the source size
+func validateDimension(dimensionStr string) (int, error) {
+	if dimensionStr == "" {
+		return 0, nil
+	}
+
+	dimension, err := strconv.Atoi(dimensionStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkDimension(dimension); err != nil {
+		return 0, err
+	}
+
+	return dimension, nil
+}
+
+// checkDimension checks a parsed width or height is in range
+func checkDimension(dimension int) error {
+	if dimension < 0 || dimension > compression.MaxDimension {
+		return fmt.Errorf("dimension must be between 0 and %d", compression.MaxDimension)
+	}
+	return nil
+}
+
+// validateScale validates the scale factor parameter, 0 means keep the source size
+func validateScale(scaleStr string) (float64, error) {
+	if scaleStr == "" {
+		return 0, nil
+	}
+
+	scale, err := strconv.ParseFloat(scaleStr, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if scale <= 0 {
+		return 0, fmt.Errorf("scale must be greater than 0 and at most 1")
+	}
+	if err := checkScale(scale); err != nil {
+		return 0, err
+	}
+
+	return scale, nil
+}
+
+// checkScale checks a parsed scale factor is in range, 0 means keep the source size
+func checkScale(scale float64) error {
+	if scale < 0 || scale > 1 || math.IsNaN(scale) {
+		return fmt.Errorf("scale must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// validatePage validates the page of a multi-page TIFF, counting from 0
+func validatePage(pageStr string) (int, error) {
+	if pageStr == "" {
+		return 0, nil
+	}
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkPage(page); err != nil {
+		return 0, err
+	}
+
+	return page, nil
+}
+
+// checkPage checks a parsed page number is not negative
+func checkPage(page int) error {
+	if page < 0 {
+		return fmt.Errorf("page must be 0 or more, pages count from 0")
+	}
+	return nil
+}
+
+// validateBool validates a boolean flag parameter
+func validateBool(flagStr string, defaultValue bool) (bool, error) {
+	if flagStr == "" {
+		return defaultValue, nil
+	}
+
+	flag, err := strconv.ParseBool(flagStr)
+	if err != nil {
+		return defaultValue, err
+	}
+
+	return flag, nil
+}
+
+// validateBackground parses the letterbox color, nil selects the default
+func validateBackground(backgroundStr string) (color.Color, error) {
+	if backgroundStr == "" {
+		return nil, nil
+	}
+
+	background, err := compression.ParseColor(backgroundStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return background, nil
+}
+
+// validateMaxBytes validates the byte budget parameter, 0 means no budget
+func validateMaxBytes(maxBytesStr string) (int, error) {
+	if maxBytesStr == "" {
+		return 0, nil
+	}
+
+	maxBytes, err := strconv.Atoi(maxBytesStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkMaxBytes(maxBytes); err != nil {
+		return 0, err
+	}
+
+	return maxBytes, nil
+}
+
+// checkMaxBytes checks a parsed byte budget is not negative
+func checkMaxBytes(maxBytes int) error {
+	if maxBytes < 0 {
+		return fmt.Errorf("max_bytes must not be negative")
+	}
+	return nil
+}
+
+// validateTargetSSIM validates the SSIM target parameter, 0 means the algorithm default
+func validateTargetSSIM(targetStr string) (float64, error) {
+	if targetStr == "" {
+		return 0, nil
+	}
+
+	target, err := strconv.ParseFloat(targetStr, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if target <= 0 {
+		return 0, fmt.Errorf("target_ssim must be between 0 and 1")
+	}
+	if err := checkTargetSSIM(target); err != nil {
+		return 0, err
+	}
+
+	return target, nil
+}
+
+// checkTargetSSIM checks a parsed SSIM target is in range, 0 means the algorithm default
+func checkTargetSSIM(target float64) error {
+	if target < 0 || target > 1 || math.IsNaN(target) {
+		return fmt.Errorf("target_ssim must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateNearLossless validates a libwebp near-lossless level, where 100 is
+// off and 0 the strongest, and returns it as a strength; unset means off
+func validateNearLossless(levelStr string) (int, error) {
+	if levelStr == "" {
+		return 0, nil
+	}
+
+	level, err := strconv.Atoi(levelStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if level < 0 || level > compression.MaxNearLossless {
+		return 0, fmt.Errorf("near_lossless must be between 0 and %d", compression.MaxNearLossless)
+	}
+
+	return compression.NearLosslessStrength(level), nil
+}
+
+// validateExact validates the exact-alpha flag, which only applies to lossless output
+func validateExact(exactStr string, lossless bool) (bool, error) {
+	exact, err := validateBool(exactStr, false)
+	if err != nil {
+		return false, err
+	}
+
+	if exact && !lossless {
+		return false, fmt.Errorf("exact requires lossless or near_lossless")
+	}
+
+	return exact, nil
+}
+
+// validateColors validates the palette size parameter, 0 means no quantization
+func validateColors(colorsStr string) (int, error) {
+	if colorsStr == "" {
+		return 0, nil
+	}
+
+	colors, err := strconv.Atoi(colorsStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkColors(colors); err != nil {
+		return 0, err
+	}
+
+	return colors, nil
+}
+
+// checkColors checks a parsed palette size is in range, 0 means no quantization
+func checkColors(colors int) error {
+	if colors != 0 && (colors < 2 || colors > 256) {
+		return fmt.Errorf("colors must be between 2 and 256")
+	}
+	return nil
+}
+
+// validateFormat validates the format parameter against the registered encoders
+func validateFormat(format, defaultFormat string, processor *compression.ImageProcessor) (string, error) {
 	if format == "" {
-		return defaultFormat
+		return defaultFormat, nil
 	}
 
-	// Add validation logic for supported formats
-	validFormats := map[string]bool{
-		"webp": true,
-		"jpeg": true,
-		"jpg":  true,
-		"png":  true,
+	// Auto and best pick among the registered encoders
+	if format == compression.FormatAuto || format == compression.FormatBest {
+		return format, nil
 	}
-	
-	if !validFormats[format] {
-		return defaultFormat
+
+	if _, ok := processor.GetEncoder(format); !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
 	}
-	
-	return format
+
+	return format, nil
 }
 
-// validateAlgorithm validates the algorithm parameter
-func validateAlgorithm(algorithm, defaultAlgorithm string) string {
-	if algorithm == "" {
-		return defaultAlgorithm
+// negotiateFormat resolves format=auto against the Accept header. WebP wins when
+// the client takes it; otherwise the processor picks JPEG or PNG from the image.
+func negotiateFormat(accept string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		if !strings.EqualFold(strings.TrimSpace(mediaType), "image/webp") {
+			continue
+		}
+
+		// An explicit q=0 means the client refuses WebP
+		refused := false
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q == 0 {
+					refused = true
+				}
+			}
+		}
+		if !refused {
+			return "webp"
+		}
 	}
-	
-	// Add validation logic for supported algorithms
-	validAlgorithms := map[string]bool{
-		"scale":      true,
-		"qualitymod": true,
+
+	return compression.FormatAuto
+}
+
+// ValidateAlgorithm reports an error wrapping compression.ErrUnknownAlgorithm when
+// the named algorithm is not registered; the empty name selects the default
+func (s *Service) ValidateAlgorithm(algorithm string) error {
+	_, err := s.processor.ResolveAlgorithm(algorithm)
+	return err
+}
+
+// ValidateFormat resolves the requested output format, the empty name selecting
+// the configured default, and reports an error wrapping ErrUnsupportedFormat when
+// no registered encoder produces it
+func (s *Service) ValidateFormat(format string) (string, error) {
+	return validateFormat(format, s.defaultFormat, s.processor)
+}
+
+// validateAlgorithm validates the algorithm parameter against the registered algorithms
+func validateAlgorithm(algorithm, defaultAlgorithm string, processor *compression.ImageProcessor) (string, error) {
+	if algorithm == "" {
+		return defaultAlgorithm, nil
 	}
-	
-	if !validAlgorithms[algorithm] {
-		return defaultAlgorithm
+
+	if _, err := processor.ResolveAlgorithm(algorithm); err != nil {
+		return "", err
 	}
-	
-	return algorithm
+
+	return algorithm, nil
 }
 
 // GetWorkerCount returns the total number of workers
@@ -240,6 +762,16 @@ func (s *Service) GetBusyWorkerCount() int {
 	return s.workerPool.BusyWorkerCount()
 }
 
+// RetryAfter suggests how long a shed client should wait before retrying, in
+// whole seconds and at least one
+func (s *Service) RetryAfter() time.Duration {
+	wait := s.workerPool.EstimatedWait().Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
 // GetServiceHealth returns the health status of the service
 func (s *Service) GetServiceHealth() bool {
 	// Check if worker pool is operational
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
+)
+
+func newTestService() *Service {
+	processor := compression.NewImageProcessor()
+	return &Service{
+		processor:        processor,
+		defaultQuality:   80,
+		defaultFormat:    "jpeg",
+		defaultAlgorithm: processor.GetDefaultAlgorithm().Name(),
+	}
+}
+
+func TestTransportsRejectTheSameSettings(t *testing.T) {
+	service := newTestService()
+
+	tests := []struct {
+		field  string
+		value  string
+		params compression.CompressionParams
+	}{
+		{"quality", "101", compression.CompressionParams{Quality: 101}},
+		{"width", "-1", compression.CompressionParams{Width: -1}},
+		{"height", "100000", compression.CompressionParams{Height: 100000}},
+		{"scale", "1.5", compression.CompressionParams{Scale: 1.5}},
+		{"max_bytes", "-10", compression.CompressionParams{MaxBytes: -10}},
+		{"target_ssim", "1.2", compression.CompressionParams{TargetSSIM: 1.2}},
+		{"page", "-1", compression.CompressionParams{Page: -1}},
+		{"colors", "1", compression.CompressionParams{Colors: 1}},
+		{"colors", "500", compression.CompressionParams{Colors: 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			form := url.Values{tt.field: {tt.value}}
+			r := httptest.NewRequest("POST", "/compress", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if _, _, _, err := service.parseParameters(r); err == nil {
+				t.Errorf("HTTP accepted %s=%s", tt.field, tt.value)
+			}
+
+			if err := service.ValidateParams(&tt.params); err == nil {
+				t.Errorf("gRPC accepted %s=%s", tt.field, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseParametersRejectsMalformedNumbers(t *testing.T) {
+	service := newTestService()
+
+	for _, field := range []string{"quality", "width", "height", "scale", "max_bytes", "target_ssim"} {
+		r := httptest.NewRequest("POST", "/compress?"+field+"=abc", nil)
+		if _, _, _, err := service.parseParameters(r); err == nil {
+			t.Errorf("HTTP accepted %s=abc", field)
+		}
+	}
+}
+
+func TestParseParametersRejectsUnknownNames(t *testing.T) {
+	service := newTestService()
+
+	tests := []struct {
+		field string
+		value string
+	}{
+		{"metadata", "copyrigth"},
+		{"progressive", "yes please"},
+		{"optimize_huffman", "maybe"},
+		{"subsampling", "4:1:1"},
+		{"fit", "contian"},
+		{"gravity", "up"},
+		{"background", "#12345"},
+		{"kernel", "lanczos4"},
+		{"png_compression", "ultra"},
+		{"dither", "sometimes"},
+		{"auto_orient", "upright"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/compress?"+tt.field+"="+url.QueryEscape(tt.value), nil)
+		if _, _, _, err := service.parseParameters(r); err == nil || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("%s=%s: err = %v, want an error naming the field", tt.field, tt.value, err)
+		}
+	}
+}
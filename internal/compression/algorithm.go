@@ -1,15 +1,137 @@
 package compression
 
 import (
+	"context"
 	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/jpegenc"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/metadata"
 )
 
 type CompressionParams struct {
+	// Quality is the encoder quality (1-100); it no longer affects dimensions
 	Quality int
+
+	// Width and Height request explicit output dimensions. If only one is set
+	// the other follows the aspect ratio. Zero means keep the source size.
+	Width  int
+	Height int
+
+	// Scale multiplies both dimensions when Width and Height are not set
+	Scale float64
+
+	// Fit, Gravity and Background control how the image fills a width x height box
+	Fit        FitMode
+	Gravity    Gravity
+	Background color.Color
+
+	// MaxPixels, when positive, caps the resize output width times height.
+	// ImageProcessor fills it in from its own pixel limit.
+	MaxPixels int64
+
+	// Kernel is the resampling filter used for any resize
+	Kernel Kernel
+
+	// DisableAutoOrient skips rotating the image according to its EXIF orientation
+	DisableAutoOrient bool
+
+	// Metadata decides which EXIF, ICC and XMP data is copied to the output
+	Metadata metadata.Policy
+
+	// Page selects the page of a multi-page TIFF, counting from 0
+	Page int
+
+	// MaxBytes, when positive, is the byte budget the encoded output must fit in.
+	// Quality then acts as an upper bound for the search.
+	MaxBytes int
+
+	// TargetSSIM is the minimum structural similarity a perceptual search must reach
+	TargetSSIM float64
+
+	// Lossless selects lossless WebP encoding; Quality is then ignored
+	Lossless bool
+
+	// NearLosslessStrength (0-100) quantizes busy pixels before a lossless
+	// encode. Zero disables it, 100 is the strongest and it implies Lossless.
+	// Requests give libwebp levels, see NearLosslessStrength.
+	NearLosslessStrength int
+
+	// Exact keeps the RGB values of fully transparent pixels in lossless output
+	Exact bool
+
+	// PNGCompression is the zlib effort for PNG output
+	PNGCompression png.CompressionLevel
+
+	// Colors, when positive, quantizes PNG output to a palette of at most this many colors
+	Colors int
+
+	// DisableDither turns off error diffusion when quantizing to a palette
+	DisableDither bool
+
+	// Progressive writes JPEG output as progressive scans
+	Progressive bool
+
+	// ChromaSubsampling is the JPEG chroma resolution, 4:2:0 by default
+	ChromaSubsampling jpegenc.Subsampling
+
+	// OptimizeHuffman builds JPEG Huffman tables for the image instead of using the typical ones
+	OptimizeHuffman bool
+}
+
+// Algorithm is a compression strategy. Process prepares the pixels that get
+// encoded; it can fail, and should stop early once ctx is done.
+type Algorithm interface {
+	Name() string
+
+	Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error)
 }
 
+// EncodingAlgorithm is an Algorithm that also writes the encoded output
+// itself, like a quality search or an external encoder library. Its bytes are
+// used as they are, without decoding them back into the pipeline.
+type EncodingAlgorithm interface {
+	Algorithm
+
+	Encode(ctx context.Context, p *ImageProcessor, img image.Image, format string, params CompressionParams) (*EncodeResult, error)
+}
+
+// SourceAlgorithm is an Algorithm that can also work straight from the
+// encoded input, decoding, resizing and encoding within one library so no
+// pixels are handed back and forth. EncodeSource reports false, with no
+// error, for inputs or settings it leaves to the pixel pipeline.
+type SourceAlgorithm interface {
+	Algorithm
+
+	EncodeSource(ctx context.Context, source []byte, format string, params CompressionParams) (*EncodeResult, bool, error)
+}
+
+// CompressionAlgorithm is the original pixel-only interface with no context
+// and no error. Wrap implementations with AdaptAlgorithm to register them.
 type CompressionAlgorithm interface {
 	Name() string
 	
 	CompressImage(img image.Image, params CompressionParams) image.Image
+}
+
+// AdaptAlgorithm lets a CompressionAlgorithm be registered as an Algorithm
+func AdaptAlgorithm(algorithm CompressionAlgorithm) Algorithm {
+	return &adaptedAlgorithm{algorithm: algorithm}
+}
+
+// adaptedAlgorithm runs a CompressionAlgorithm, checking ctx before it starts
+type adaptedAlgorithm struct {
+	algorithm CompressionAlgorithm
+}
+
+func (a *adaptedAlgorithm) Name() string {
+	return a.algorithm.Name()
+}
+
+func (a *adaptedAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.algorithm.CompressImage(img, params), nil
 }
\ No newline at end of file
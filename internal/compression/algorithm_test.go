@@ -0,0 +1,83 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// invertAlgorithm is written against the old CompressionAlgorithm interface
+type invertAlgorithm struct {
+	calls int
+}
+
+func (a *invertAlgorithm) Name() string { return "invert" }
+
+func (a *invertAlgorithm) CompressImage(img image.Image, params CompressionParams) image.Image {
+	a.calls++
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			out.SetRGBA(x, y, color.RGBA{255 - c.R, 255 - c.G, 255 - c.B, c.A})
+		}
+	}
+	return out
+}
+
+func TestAdaptedAlgorithmRegistersAndRuns(t *testing.T) {
+	processor := NewImageProcessor()
+	legacy := &invertAlgorithm{}
+	processor.RegisterAlgorithm(AdaptAlgorithm(legacy))
+
+	algorithm, err := processor.ResolveAlgorithm("invert")
+	if err != nil {
+		t.Fatalf("ResolveAlgorithm: %v", err)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range src.Pix {
+		src.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encoding source: %v", err)
+	}
+
+	result, err := processor.ProcessImage(context.Background(), &buf, "png", CompressionParams{Quality: 80}, algorithm)
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	if legacy.calls != 1 {
+		t.Errorf("CompressImage called %d times, want 1", legacy.calls)
+	}
+
+	out, err := png.Decode(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if got := color.RGBAModel.Convert(out.At(1, 1)); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("output pixel is %v, want the inverted black", got)
+	}
+}
+
+func TestAdaptedAlgorithmChecksContext(t *testing.T) {
+	legacy := &invertAlgorithm{}
+	algorithm := AdaptAlgorithm(legacy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := algorithm.Process(ctx, image.NewRGBA(image.Rect(0, 0, 4, 4)), CompressionParams{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if legacy.calls != 0 {
+		t.Error("CompressImage ran for a cancelled context")
+	}
+}
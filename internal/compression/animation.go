@@ -0,0 +1,305 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/gif"
+	"time"
+
+	"golang.org/x/image/draw"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+)
+
+// gifDelayUnit is the resolution of GIF frame delays
+const gifDelayUnit = 10 * time.Millisecond
+
+// maxAnimationFrames caps the frames decoded from one GIF, whatever their size
+const maxAnimationFrames = 2048
+
+// Animation is a decoded multi-frame image. Every frame is composited onto
+// the full canvas, so frames can be resized independently and disposal no
+// longer matters.
+type Animation struct {
+	Frames []image.Image
+	Delays []time.Duration
+
+	// LoopCount follows the GIF convention: 0 loops forever, -1 plays once,
+	// n plays n+1 times
+	LoopCount int
+}
+
+// isGIF reports whether data starts with a GIF signature
+func isGIF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+}
+
+// decodeGIFAnimation decodes every frame of a GIF and plays the frames out
+// onto the canvas the way a viewer would. Every composited frame is kept, so
+// frames times canvas pixels is checked against maxPixels from the block
+// headers before anything is decoded; zero or less only caps the frame count.
+func decodeGIFAnimation(data []byte, maxPixels int64) (*Animation, error) {
+	frames, pixels := scanGIF(data)
+	if frames > maxAnimationFrames {
+		return nil, fmt.Errorf("%w: gif has %d frames, the limit is %d", ErrImageTooLarge, frames, maxAnimationFrames)
+	}
+	if maxPixels > 0 && pixels > maxPixels {
+		return nil, fmt.Errorf("%w: gif frames decode to %d pixels, over the %d pixel limit", ErrImageTooLarge, pixels, maxPixels)
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding gif: %v", err)
+	}
+
+	canvasRect := image.Rect(0, 0, decoded.Config.Width, decoded.Config.Height)
+	if canvasRect.Empty() && len(decoded.Image) > 0 {
+		canvasRect = decoded.Image[0].Bounds()
+	}
+
+	animation := &Animation{LoopCount: decoded.LoopCount}
+	canvas := image.NewRGBA(canvasRect)
+	for i, frame := range decoded.Image {
+		var previous *image.RGBA
+		disposal := byte(gif.DisposalNone)
+		if i < len(decoded.Disposal) {
+			disposal = decoded.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		animation.Frames = append(animation.Frames, cloneRGBA(canvas))
+
+		delay := 0
+		if i < len(decoded.Delay) {
+			delay = decoded.Delay[i]
+		}
+		animation.Delays = append(animation.Delays, time.Duration(delay)*gifDelayUnit)
+
+		// Viewers clear to transparent rather than the background color
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return animation, nil
+}
+
+// processAnimation compresses every frame of an animation. GIF and WebP
+// output keep the frames; other formats get the first frame. FormatAuto
+// becomes GIF so the animation survives, and FormatBest picks between GIF
+// and WebP like it does between still formats. A byte budget gets the same quality-then-downscale search
+// as a still image, applied to every frame.
+func (p *ImageProcessor) processAnimation(ctx context.Context, animation *Animation, format string, params CompressionParams, algorithm Algorithm) (*EncodeResult, error) {
+	switch format {
+	case FormatAuto:
+		format = "gif"
+	case FormatBest:
+		return p.encodeBestAnimation(ctx, animation, params, algorithm)
+	}
+
+	if format != "gif" && format != "webp" {
+		compressedImg, err := algorithm.Process(ctx, animation.Frames[0], params)
+		if err != nil {
+			return nil, fmt.Errorf("%s algorithm: %w", algorithm.Name(), err)
+		}
+		result, err := p.encodeImage(ctx, compressedImg, format, params, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		result.Format = format
+		return result, nil
+	}
+
+	result, _, err := p.compressAnimation(ctx, animation, format, params, algorithm)
+	return result, err
+}
+
+// encodeBestAnimation encodes the animation to WebP and GIF and keeps the
+// smallest that meets the SSIM floor, the same rule as encodeBest. Each
+// candidate is scored on its first frame.
+func (p *ImageProcessor) encodeBestAnimation(ctx context.Context, animation *Animation, params CompressionParams, algorithm Algorithm) (*EncodeResult, error) {
+	floor := params.TargetSSIM
+	if floor <= 0 {
+		floor = DefaultTargetSSIM
+	}
+
+	var best *EncodeResult
+	passes := 0
+	for _, format := range []string{"webp", "gif"} {
+		result, compressed, err := p.compressAnimation(ctx, animation, format, params, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		passes += result.Passes
+
+		if result.SSIM, err = p.scoreFrame(compressed.Frames[0], format, params, result.Quality); err != nil {
+			return nil, fmt.Errorf("%s: %w", format, err)
+		}
+		if betterCandidate(result, best, floor) {
+			best = result
+		}
+	}
+
+	best.Passes = passes
+	metrics.RecordBestFormat(best.Format)
+	return best, nil
+}
+
+// scoreFrame measures the SSIM of one frame encoded the way the animated
+// encoders encode each frame
+func (p *ImageProcessor) scoreFrame(frame image.Image, format string, params CompressionParams, quality int) (float64, error) {
+	attempt := params
+	attempt.Quality = quality
+	data, err := p.EncodeToFormat(frame, format, attempt)
+	if err != nil {
+		return 0, err
+	}
+	scored, err := scoreEncoding(frame, data, quality)
+	if err != nil {
+		return 0, err
+	}
+	return scored.SSIM, nil
+}
+
+// compressAnimation runs the algorithm on every frame and encodes them to an
+// animated GIF or WebP. It also returns the frames as encoded, after any
+// downscaling for the byte budget.
+func (p *ImageProcessor) compressAnimation(ctx context.Context, animation *Animation, format string, params CompressionParams, algorithm Algorithm) (*EncodeResult, *Animation, error) {
+	compressed := &Animation{
+		Frames:    make([]image.Image, len(animation.Frames)),
+		Delays:    animation.Delays,
+		LoopCount: animation.LoopCount,
+	}
+	for i, frame := range animation.Frames {
+		compressedFrame, err := algorithm.Process(ctx, frame, params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s algorithm: %w", algorithm.Name(), err)
+		}
+		compressed.Frames[i] = compressedFrame
+	}
+
+	encode := func(q int) ([]byte, error) {
+		attempt := params
+		attempt.Quality = q
+		return encodeAnimation(compressed, format, attempt)
+	}
+
+	if params.MaxBytes <= 0 {
+		data, err := encode(params.Quality)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &EncodeResult{
+			Data:    data,
+			Format:  format,
+			Quality: params.Quality,
+			Passes:  1,
+		}, compressed, nil
+	}
+
+	// Search quality, then scale every frame down together, like still images
+	shrink := func() bool {
+		frames := make([]image.Image, len(compressed.Frames))
+		for i, frame := range compressed.Frames {
+			scaled, ok := shrinkForBudget(frame, params.Kernel)
+			if !ok {
+				return false
+			}
+			frames[i] = scaled
+		}
+		compressed.Frames = frames
+		return true
+	}
+	result, err := searchBudget(ctx, format, params, encode, shrink)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.Format = format
+	return result, compressed, nil
+}
+
+// encodeAnimation encodes every frame of animation to an animated GIF or WebP
+func encodeAnimation(animation *Animation, format string, params CompressionParams) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "webp" {
+		err = encodeAnimatedWebP(&buf, animation, params)
+	} else {
+		err = encodeAnimatedGIF(&buf, animation, params)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding image to %s: %v", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scanGIF walks the GIF block structure without decoding any pixels. It
+// returns the number of frames and the pixels they take once each is
+// composited onto the canvas. The walk stops at the first malformed block,
+// leaving the decoder to report the error.
+func scanGIF(data []byte) (frames int, pixels int64) {
+	if !isGIF(data) || len(data) < 13 {
+		return 0, 0
+	}
+
+	canvas := int64(binary.LittleEndian.Uint16(data[6:8])) * int64(binary.LittleEndian.Uint16(data[8:10]))
+	offset := 13
+	if data[10]&0x80 != 0 {
+		offset += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks returns the offset after a chain of data sub-blocks, or -1
+	skipSubBlocks := func(offset int) int {
+		for offset < len(data) {
+			size := int(data[offset])
+			offset++
+			if size == 0 {
+				return offset
+			}
+			offset += size
+		}
+		return -1
+	}
+
+	for offset >= 0 && offset < len(data) {
+		switch data[offset] {
+		case 0x2C: // image descriptor
+			if offset+10 > len(data) {
+				return frames, pixels
+			}
+			descriptor := data[offset+1 : offset+10]
+			frame := int64(binary.LittleEndian.Uint16(descriptor[4:6])) * int64(binary.LittleEndian.Uint16(descriptor[6:8]))
+			frames++
+			pixels += max(canvas, frame)
+
+			offset += 10
+			if descriptor[8]&0x80 != 0 {
+				offset += 3 << (descriptor[8]&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data
+			offset = skipSubBlocks(offset + 1)
+		case 0x21: // extension: label, then data
+			offset = skipSubBlocks(offset + 2)
+		default: // trailer or garbage
+			return frames, pixels
+		}
+	}
+
+	return frames, pixels
+}
+
+// cloneRGBA copies an RGBA image
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
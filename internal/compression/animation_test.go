@@ -0,0 +1,136 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+// encodeTestGIF builds an animated GIF with the given number of frames, each
+// frame covering the top-left quarter of a width x height canvas
+func encodeTestGIF(t *testing.T, frames, width, height int) []byte {
+	t.Helper()
+
+	animation := &gif.GIF{
+		Config: image.Config{ColorModel: color.Palette(palette.Plan9), Width: width, Height: height},
+	}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width/2, height/2), palette.Plan9)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i + j)
+		}
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 5)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatalf("encoding test gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestScanGIF(t *testing.T) {
+	data := encodeTestGIF(t, 5, 40, 30)
+
+	frames, pixels := scanGIF(data)
+	if frames != 5 {
+		t.Errorf("frames = %d, want 5", frames)
+	}
+	if want := int64(5 * 40 * 30); pixels != want {
+		t.Errorf("pixels = %d, want %d", pixels, want)
+	}
+}
+
+func TestDecodeGIFAnimationPixelLimit(t *testing.T) {
+	data := encodeTestGIF(t, 5, 40, 30)
+
+	animation, err := decodeGIFAnimation(data, 5*40*30)
+	if err != nil {
+		t.Fatalf("decodeGIFAnimation at the limit: %v", err)
+	}
+	if len(animation.Frames) != 5 {
+		t.Errorf("decoded %d frames, want 5", len(animation.Frames))
+	}
+
+	if _, err := decodeGIFAnimation(data, 5*40*30-1); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("decodeGIFAnimation over the limit: err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestDecodeGIFAnimationFrameLimit(t *testing.T) {
+	data := encodeTestGIF(t, maxAnimationFrames+1, 2, 2)
+
+	if _, err := decodeGIFAnimation(data, 0); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestProcessAnimationFitsBudget(t *testing.T) {
+	processor := NewImageProcessor()
+	source := encodeTestGIF(t, 3, 128, 128)
+	algorithm := processor.GetDefaultAlgorithm()
+
+	for _, format := range []string{"gif", "webp"} {
+		t.Run(format, func(t *testing.T) {
+			full, err := processor.ProcessImage(context.Background(), bytes.NewReader(source), format, CompressionParams{Quality: 90}, algorithm)
+			if err != nil {
+				t.Fatalf("ProcessImage without budget: %v", err)
+			}
+
+			budget := len(full.Data) / 2
+			result, err := processor.ProcessImage(context.Background(), bytes.NewReader(source), format, CompressionParams{Quality: 90, MaxBytes: budget}, algorithm)
+			if err != nil {
+				t.Fatalf("ProcessImage with a %d byte budget: %v", budget, err)
+			}
+			if len(result.Data) > budget {
+				t.Errorf("output is %d bytes, over the %d byte budget", len(result.Data), budget)
+			}
+			if result.Passes < 2 || result.Format != format {
+				t.Errorf("%s after %d passes, want a %s search", result.Format, result.Passes, format)
+			}
+
+			if format == "gif" {
+				decoded, err := gif.DecodeAll(bytes.NewReader(result.Data))
+				if err != nil {
+					t.Fatalf("decoding output: %v", err)
+				}
+				if len(decoded.Image) != 3 {
+					t.Errorf("output has %d frames, want 3", len(decoded.Image))
+				}
+			}
+		})
+	}
+}
+
+func TestProcessAnimationBestKeepsTheSSIMFloor(t *testing.T) {
+	processor := NewImageProcessor()
+	source := encodeTestGIF(t, 3, 64, 64)
+	algorithm := processor.GetDefaultAlgorithm()
+	params := CompressionParams{Quality: 5, TargetSSIM: 0.99}
+
+	sizes := map[string]int{}
+	for _, format := range []string{"webp", "gif"} {
+		result, err := processor.ProcessImage(context.Background(), bytes.NewReader(source), format, params, algorithm)
+		if err != nil {
+			t.Fatalf("ProcessImage(%s): %v", format, err)
+		}
+		sizes[format] = len(result.Data)
+	}
+
+	best, err := processor.ProcessImage(context.Background(), bytes.NewReader(source), FormatBest, params, algorithm)
+	if err != nil {
+		t.Fatalf("ProcessImage(best): %v", err)
+	}
+	if sizes["webp"] >= sizes["gif"] {
+		t.Fatalf("webp is %d bytes and gif %d, want webp to be the smaller", sizes["webp"], sizes["gif"])
+	}
+	if best.Format != "gif" || best.SSIM < params.TargetSSIM {
+		t.Errorf("picked %s at SSIM %.4f, want the gif meeting the %.2f floor", best.Format, best.SSIM, params.TargetSSIM)
+	}
+}
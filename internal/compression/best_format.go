@@ -0,0 +1,162 @@
+package compression
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync/atomic"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
+)
+
+// FormatBest encodes to every suitable format and keeps the smallest output
+// that still reaches the SSIM floor (TargetSSIM, or DefaultTargetSSIM)
+const FormatBest = "best"
+
+// bestCandidates are the formats tried for FormatBest, in order of preference on ties
+var bestCandidates = []string{"webp", "jpeg", "png"}
+
+// SetPool lets the processor fan candidate encodes out onto a worker pool.
+// Without one, candidates are encoded one after another.
+func (p *ImageProcessor) SetPool(pool *worker.Pool) {
+	p.pool = pool
+}
+
+// encodeBest encodes img to each candidate format and picks the smallest one
+// meeting the SSIM floor. When none does, the closest to the source wins.
+func (p *ImageProcessor) encodeBest(ctx context.Context, img image.Image, params CompressionParams, algorithm Algorithm) (*EncodeResult, error) {
+	floor := params.TargetSSIM
+	if floor <= 0 {
+		floor = DefaultTargetSSIM
+	}
+
+	// JPEG would flatten transparency
+	opaque := isOpaque(img)
+	var candidates []*candidateEncode
+	for _, format := range bestCandidates {
+		if format == "jpeg" && !opaque {
+			continue
+		}
+		candidates = append(candidates, &candidateEncode{
+			processor: p,
+			img:       img,
+			format:    format,
+			params:    params,
+			algorithm: algorithm,
+			done:      make(chan struct{}),
+		})
+	}
+
+	p.runCandidates(ctx, candidates)
+
+	var best *EncodeResult
+	var firstErr error
+	passes := 0
+	for _, candidate := range candidates {
+		if candidate.err != nil {
+			if firstErr == nil {
+				firstErr = candidate.err
+			}
+			continue
+		}
+
+		result := candidate.result
+		passes += result.Passes
+		if betterCandidate(result, best, floor) {
+			best = result
+		}
+	}
+
+	if best == nil {
+		return nil, firstErr
+	}
+
+	best.Passes = passes
+	metrics.RecordBestFormat(best.Format)
+	return best, nil
+}
+
+// betterCandidate reports whether result beats best, which may be nil: the
+// smallest output meeting the SSIM floor wins, and while none does, the
+// closest to the source
+func betterCandidate(result, best *EncodeResult, floor float64) bool {
+	switch {
+	case best == nil:
+		return true
+	case result.SSIM >= floor:
+		return best.SSIM < floor || len(result.Data) < len(best.Data)
+	default:
+		return best.SSIM < floor && result.SSIM > best.SSIM
+	}
+}
+
+// runCandidates encodes the candidates, offering all but the first to the
+// pool. Whatever no worker has started yet is encoded right here, so a job
+// waiting on its candidates can never deadlock a saturated pool.
+func (p *ImageProcessor) runCandidates(ctx context.Context, candidates []*candidateEncode) {
+	if p.pool != nil {
+		for _, candidate := range candidates[1:] {
+			if !p.pool.TrySubmit(ctx, candidate, make(chan worker.JobResult, 1), make(chan error, 1)) {
+				break
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		candidate.Process(ctx)
+	}
+
+	for _, candidate := range candidates {
+		<-candidate.done
+	}
+}
+
+// candidateEncode is one format tried by encodeBest. Whoever claims it first,
+// a pool worker or the waiting job, does the encode.
+type candidateEncode struct {
+	processor *ImageProcessor
+	img       image.Image
+	format    string
+	params    CompressionParams
+	algorithm Algorithm
+
+	claimed atomic.Bool
+	done    chan struct{}
+
+	result *EncodeResult
+	err    error
+}
+
+// ID returns the candidate format
+func (c *candidateEncode) ID() string {
+	return c.format
+}
+
+// Process encodes and scores the candidate unless someone already has
+func (c *candidateEncode) Process(ctx context.Context) (worker.JobResult, error) {
+	if !c.claimed.CompareAndSwap(false, true) {
+		return c, nil
+	}
+	defer close(c.done)
+
+	result, err := c.processor.encodeImage(ctx, c.img, c.format, c.params, c.algorithm)
+	if err != nil {
+		c.err = fmt.Errorf("%s: %w", c.format, err)
+		return c, nil
+	}
+
+	// Searchers already measured their output
+	if result.SSIM == 0 {
+		scored, err := scoreEncoding(c.img, result.Data, result.Quality)
+		if err != nil {
+			c.err = fmt.Errorf("%s: %w", c.format, err)
+			return c, nil
+		}
+		result.SSIM = scored.SSIM
+	}
+
+	result.Format = c.format
+	c.result = result
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package compression
+
+import (
+	"image"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/icc"
+)
+
+// convertToSRGB converts an image tagged with a wide-gamut ICC profile to sRGB.
+// It reports false and returns img unchanged when there is no profile, the
+// profile is already sRGB, or the profile is malformed or not a supported
+// matrix/TRC profile.
+func convertToSRGB(img image.Image, iccData []byte) (image.Image, bool) {
+	if len(iccData) == 0 {
+		return img, false
+	}
+
+	profile, err := icc.Parse(iccData)
+	if err != nil || profile.IsSRGB() {
+		return img, false
+	}
+
+	return profile.ConvertToSRGB(img), true
+}
+
+// needsSRGBConversion reports whether convertToSRGB would change an image
+// tagged with iccData, without touching any pixels
+func needsSRGBConversion(iccData []byte) bool {
+	if len(iccData) == 0 {
+		return false
+	}
+	profile, err := icc.Parse(iccData)
+	return err == nil && !profile.IsSRGB()
+}
@@ -0,0 +1,128 @@
+package compression
+
+import (
+	"image"
+	"io"
+	"sort"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// Encoder writes images in one output format
+type Encoder interface {
+	// Name is the format name requests select the encoder by
+	Name() string
+
+	// MIMEType is the Content-Type of the encoded output
+	MIMEType() string
+
+	// Extension is the file extension for the encoded output, without the dot
+	Extension() string
+
+	// Options lists the request parameters the encoder honors
+	Options() []string
+
+	Encode(w io.Writer, img image.Image, params CompressionParams) error
+}
+
+// EncodeFunc encodes img with the encoder settings in params
+type EncodeFunc func(w io.Writer, img image.Image, params CompressionParams) error
+
+// formatEncoder is an Encoder built from its description and an encode function
+type formatEncoder struct {
+	name      string
+	mimeType  string
+	extension string
+	options   []string
+	encode    EncodeFunc
+}
+
+// NewEncoder creates an Encoder from a format description and an encode function
+func NewEncoder(name, mimeType, extension string, options []string, encode EncodeFunc) Encoder {
+	return &formatEncoder{
+		name:      name,
+		mimeType:  mimeType,
+		extension: extension,
+		options:   options,
+		encode:    encode,
+	}
+}
+
+func (e *formatEncoder) Name() string      { return e.name }
+func (e *formatEncoder) MIMEType() string  { return e.mimeType }
+func (e *formatEncoder) Extension() string { return e.extension }
+func (e *formatEncoder) Options() []string { return e.options }
+
+func (e *formatEncoder) Encode(w io.Writer, img image.Image, params CompressionParams) error {
+	return e.encode(w, img, params)
+}
+
+// registerBuiltinEncoders registers the formats supported out of the box
+func (p *ImageProcessor) registerBuiltinEncoders() {
+	p.RegisterEncoder(NewEncoder("webp", "image/webp", "webp",
+		[]string{"quality", "max_bytes", "lossless", "near_lossless", "exact"},
+		encodeWebP))
+
+	p.RegisterEncoder(NewEncoder("jpeg", "image/jpeg", "jpg",
+		[]string{"quality", "max_bytes", "progressive", "subsampling", "optimize_huffman"},
+		encodeJPEG), "jpg")
+
+	p.RegisterEncoder(NewEncoder("png", "image/png", "png",
+		[]string{"png_compression", "colors", "dither"},
+		encodePNG))
+
+	p.RegisterEncoder(NewEncoder("gif", "image/gif", "gif",
+		[]string{"colors", "dither"},
+		encodeGIF))
+
+	p.RegisterEncoder(NewEncoder("bmp", "image/bmp", "bmp", nil,
+		func(w io.Writer, img image.Image, params CompressionParams) error {
+			return bmp.Encode(w, img)
+		}))
+
+	p.RegisterEncoder(NewEncoder("tiff", "image/tiff", "tiff", nil,
+		func(w io.Writer, img image.Image, params CompressionParams) error {
+			return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+		}), "tif")
+}
+
+// RegisterEncoder registers an output format under its name and any aliases
+func (p *ImageProcessor) RegisterEncoder(encoder Encoder, aliases ...string) {
+	p.encoders[encoder.Name()] = encoder
+	for _, alias := range aliases {
+		p.encoders[alias] = encoder
+	}
+}
+
+// GetEncoder returns the encoder registered for a format name or alias
+func (p *ImageProcessor) GetEncoder(format string) (Encoder, bool) {
+	encoder, exists := p.encoders[format]
+	return encoder, exists
+}
+
+// Encoders returns the registered encoders sorted by name, each once
+func (p *ImageProcessor) Encoders() []Encoder {
+	var encoders []Encoder
+	for name, encoder := range p.encoders {
+		if name == encoder.Name() {
+			encoders = append(encoders, encoder)
+		}
+	}
+	sort.Slice(encoders, func(i, j int) bool {
+		return encoders[i].Name() < encoders[j].Name()
+	})
+	return encoders
+}
+
+// FormatAliases returns the alternative names a format is registered under
+func (p *ImageProcessor) FormatAliases(format string) []string {
+	var aliases []string
+	for name, encoder := range p.encoders {
+		if encoder.Name() == format && name != format {
+			aliases = append(aliases, name)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
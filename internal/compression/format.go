@@ -0,0 +1,35 @@
+package compression
+
+import "image"
+
+// FormatAuto lets the processor pick the output format from the image:
+// JPEG for opaque images, PNG when there is transparency to keep
+const FormatAuto = "auto"
+
+// resolveFormat replaces FormatAuto with a concrete format for img
+func resolveFormat(format string, img image.Image) string {
+	if format != FormatAuto {
+		return format
+	}
+	if isOpaque(img) {
+		return "jpeg"
+	}
+	return "png"
+}
+
+// isOpaque reports whether every pixel of img is fully opaque
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xFFFF {
+				return false
+			}
+		}
+	}
+	return true
+}
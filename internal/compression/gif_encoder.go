@@ -0,0 +1,121 @@
+package compression
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// gifAlphaThreshold is the alpha below which a pixel becomes fully transparent,
+// GIF has no partial transparency
+const gifAlphaThreshold = 0x80
+
+// encodeGIF writes a single-frame GIF with a median-cut palette
+func encodeGIF(w io.Writer, img image.Image, params CompressionParams) error {
+	return gif.Encode(w, quantizeGIF(img, params), nil)
+}
+
+// encodeAnimatedGIF writes every frame at full canvas size with its own palette
+func encodeAnimatedGIF(w io.Writer, animation *Animation, params CompressionParams) error {
+	out := &gif.GIF{LoopCount: animation.LoopCount}
+
+	for i, frame := range animation.Frames {
+		out.Image = append(out.Image, quantizeGIF(frame, params))
+		out.Delay = append(out.Delay, gifDelay(animation.Delays[i]))
+	}
+
+	// Frames are complete canvases, but a frame with holes would show the
+	// one before it through them, so clear the canvas ahead of such frames
+	for i := range out.Image {
+		next := out.Image[(i+1)%len(out.Image)]
+		disposal := byte(gif.DisposalNone)
+		if hasTransparency(next) {
+			disposal = gif.DisposalBackground
+		}
+		out.Disposal = append(out.Disposal, disposal)
+	}
+
+	return gif.EncodeAll(w, out)
+}
+
+// quantizeGIF reduces img to a GIF palette: at most params.Colors entries
+// (256 by default), one of them fully transparent if any pixel is
+func quantizeGIF(img image.Image, params CompressionParams) *image.Paletted {
+	n := params.Colors
+	if n <= 0 || n > maxPaletteColors {
+		n = maxPaletteColors
+	}
+
+	// Snap alpha to on or off, keeping one palette slot for transparency
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	transparent := false
+	for i := 0; i < len(src.Pix); i += 4 {
+		if src.Pix[i+3] < gifAlphaThreshold {
+			src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = 0, 0, 0, 0
+			transparent = true
+		} else {
+			src.Pix[i+3] = 0xFF
+		}
+	}
+
+	var opaque []colorCount
+	for _, entry := range histogram(src) {
+		if entry.color[3] != 0 {
+			opaque = append(opaque, entry)
+		}
+	}
+
+	slots := n
+	if transparent {
+		slots--
+	}
+	var palette color.Palette
+	if len(opaque) > 0 {
+		palette = medianCut(opaque, slots)
+	}
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+	}
+
+	dst := image.NewPaletted(src.Rect, palette)
+	if params.DisableDither {
+		draw.Draw(dst, dst.Rect, src, image.Point{}, draw.Src)
+	} else {
+		draw.FloydSteinberg.Draw(dst, dst.Rect, src, image.Point{})
+	}
+	return dst
+}
+
+// hasTransparency reports whether any pixel uses a transparent palette entry
+func hasTransparency(img *image.Paletted) bool {
+	transparent := make([]bool, len(img.Palette))
+	found := false
+	for i, c := range img.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparent[i] = true
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+
+	for _, index := range img.Pix {
+		if transparent[index] {
+			return true
+		}
+	}
+	return false
+}
+
+// gifDelay rounds a frame delay to GIF's hundredths of a second
+func gifDelay(delay time.Duration) int {
+	return int((delay + gifDelayUnit/2) / gifDelayUnit)
+}
@@ -0,0 +1,166 @@
+package icc
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// srgbColorants are the D50-adapted sRGB primaries as found in the standard sRGB profile
+var srgbColorants = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// xyzToSRGB converts D50 XYZ to linear sRGB
+var xyzToSRGB = invert3(srgbColorants)
+
+// srgbTolerance is how close colorants and curves must be to count as sRGB already
+const srgbTolerance = 0.002
+
+// IsSRGB reports whether the profile is close enough to sRGB that
+// converting would only add rounding error
+func (p *Profile) IsSRGB() bool {
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if math.Abs(p.Colorants[row][col]-srgbColorants[row][col]) > srgbTolerance {
+				return false
+			}
+		}
+	}
+
+	for _, curve := range p.Curves {
+		for _, v := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+			if math.Abs(curve.Linearize(v)-srgbLinearize(v)) > srgbTolerance*5 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ConvertToSRGB returns a copy of img with its pixels moved from the
+// profile's color space to sRGB. Alpha is left untouched.
+func (p *Profile) ConvertToSRGB(img image.Image) *image.NRGBA {
+	// Per-channel linearization tables for 8-bit input
+	var linear [3][256]float64
+	for channel, curve := range p.Curves {
+		for v := 0; v < 256; v++ {
+			linear[channel][v] = curve.Linearize(float64(v) / 255)
+		}
+	}
+
+	matrix := multiply3(xyzToSRGB, p.Colorants)
+	encode := newSRGBEncoder()
+
+	bounds := img.Bounds()
+	src, ok := img.(*image.NRGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	dst := image.NewNRGBA(src.Rect)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		row := y * src.Stride
+		for x := 0; x < src.Rect.Dx(); x++ {
+			i := row + x*4
+			r := linear[0][src.Pix[i]]
+			g := linear[1][src.Pix[i+1]]
+			b := linear[2][src.Pix[i+2]]
+
+			dst.Pix[i] = encode.value(matrix[0][0]*r + matrix[0][1]*g + matrix[0][2]*b)
+			dst.Pix[i+1] = encode.value(matrix[1][0]*r + matrix[1][1]*g + matrix[1][2]*b)
+			dst.Pix[i+2] = encode.value(matrix[2][0]*r + matrix[2][1]*g + matrix[2][2]*b)
+			dst.Pix[i+3] = src.Pix[i+3]
+		}
+	}
+
+	return dst
+}
+
+// srgbEncoder maps linear light to 8-bit sRGB through a lookup table
+type srgbEncoder [4096]uint8
+
+func newSRGBEncoder() *srgbEncoder {
+	var encoder srgbEncoder
+	for i := range encoder {
+		v := float64(i) / float64(len(encoder)-1)
+		encoder[i] = uint8(math.Round(srgbCompand(v) * 255))
+	}
+	return &encoder
+}
+
+// value encodes a linear value, clipping colors outside the sRGB gamut.
+// NaN maps to 0 so a bad value can never index outside the table.
+func (e *srgbEncoder) value(v float64) uint8 {
+	if !(v > 0) {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return e[int(v*float64(len(e)-1)+0.5)]
+}
+
+// srgbLinearize is the sRGB transfer function from encoded to linear
+func srgbLinearize(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// srgbCompand is the sRGB transfer function from linear to encoded
+func srgbCompand(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// multiply3 multiplies two 3x3 matrices
+func multiply3(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			for k := 0; k < 3; k++ {
+				out[row][col] += a[row][k] * b[k][col]
+			}
+		}
+	}
+	return out
+}
+
+// determinant3 returns the determinant of a 3x3 matrix
+func determinant3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// invert3 inverts a 3x3 matrix
+func invert3(m [3][3]float64) [3][3]float64 {
+	det := determinant3(m)
+
+	return [3][3]float64{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+		},
+	}
+}
@@ -0,0 +1,314 @@
+// Package icc parses matrix/TRC ICC color profiles and converts images
+// tagged with them to sRGB
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Common errors
+var (
+	ErrInvalidProfile     = errors.New("invalid ICC profile")
+	ErrUnsupportedProfile = errors.New("unsupported ICC profile")
+)
+
+// headerSize is the fixed ICC header preceding the tag table
+const headerSize = 128
+
+// Profile is an RGB matrix/TRC profile: three tone curves that linearize
+// each channel and a matrix from linear RGB to D50 XYZ
+type Profile struct {
+	// Description is the profile name, if the desc tag could be read
+	Description string
+
+	// Colorants holds the rXYZ, gXYZ and bXYZ columns
+	Colorants [3][3]float64
+
+	// Curves linearize the red, green and blue channels
+	Curves [3]Curve
+}
+
+// Curve maps an encoded channel value in [0, 1] to linear light
+type Curve interface {
+	Linearize(v float64) float64
+}
+
+// Parse reads an ICC profile. Only RGB matrix/TRC profiles with an XYZ
+// connection space are supported; LUT-based profiles return ErrUnsupportedProfile.
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < headerSize+4 {
+		return nil, ErrInvalidProfile
+	}
+	if string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("%w: missing acsp signature", ErrInvalidProfile)
+	}
+	if colorSpace := string(data[16:20]); colorSpace != "RGB " {
+		return nil, fmt.Errorf("%w: color space %q", ErrUnsupportedProfile, colorSpace)
+	}
+	if pcs := string(data[20:24]); pcs != "XYZ " {
+		return nil, fmt.Errorf("%w: connection space %q", ErrUnsupportedProfile, pcs)
+	}
+
+	tags, err := readTagTable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{}
+	for i, signature := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		tag, ok := tags[signature]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s tag", ErrUnsupportedProfile, signature)
+		}
+		xyz, err := readXYZ(tag)
+		if err != nil {
+			return nil, err
+		}
+		for row := 0; row < 3; row++ {
+			profile.Colorants[row][i] = xyz[row]
+		}
+	}
+
+	for i, signature := range []string{"rTRC", "gTRC", "bTRC"} {
+		tag, ok := tags[signature]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s tag", ErrUnsupportedProfile, signature)
+		}
+		curve, err := readCurve(tag)
+		if err != nil {
+			return nil, err
+		}
+		profile.Curves[i] = curve
+	}
+
+	if desc, ok := tags["desc"]; ok {
+		profile.Description = readDescription(desc)
+	}
+
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// validate rejects profiles whose matrix or curves would turn pixels into
+// NaN or Inf, which a crafted profile can do with a zero or negative gamma
+func (p *Profile) validate() error {
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if !isFinite(p.Colorants[row][col]) {
+				return fmt.Errorf("%w: non-finite colorant", ErrInvalidProfile)
+			}
+		}
+	}
+	if math.Abs(determinant3(p.Colorants)) < 1e-6 {
+		return fmt.Errorf("%w: singular colorant matrix", ErrInvalidProfile)
+	}
+
+	for i, curve := range p.Curves {
+		for v := 0; v < 256; v++ {
+			if !isFinite(curve.Linearize(float64(v) / 255)) {
+				return fmt.Errorf("%w: tone curve %d is not finite on [0, 1]", ErrInvalidProfile, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isFinite reports whether v is neither NaN nor infinite
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// readTagTable maps tag signatures to their data
+func readTagTable(data []byte) (map[string][]byte, error) {
+	count := int(binary.BigEndian.Uint32(data[headerSize : headerSize+4]))
+	if count < 0 || headerSize+4+count*12 > len(data) {
+		return nil, fmt.Errorf("%w: truncated tag table", ErrInvalidProfile)
+	}
+
+	tags := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := data[headerSize+4+i*12:]
+		offset := int(binary.BigEndian.Uint32(entry[4:8]))
+		size := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("%w: tag outside profile", ErrInvalidProfile)
+		}
+		tags[string(entry[:4])] = data[offset : offset+size]
+	}
+
+	return tags, nil
+}
+
+// readXYZ reads the first value of an XYZType tag
+func readXYZ(tag []byte) ([3]float64, error) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, fmt.Errorf("%w: bad XYZ tag", ErrInvalidProfile)
+	}
+	return [3]float64{
+		s15Fixed16(tag[8:12]),
+		s15Fixed16(tag[12:16]),
+		s15Fixed16(tag[16:20]),
+	}, nil
+}
+
+// readCurve reads a curveType or parametricCurveType tag
+func readCurve(tag []byte) (Curve, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("%w: bad tone curve", ErrInvalidProfile)
+	}
+
+	switch string(tag[:4]) {
+	case "curv":
+		count := int(binary.BigEndian.Uint32(tag[8:12]))
+		switch {
+		case count == 0:
+			return gammaCurve(1), nil
+		case count == 1 && len(tag) >= 14:
+			// A single entry is a u8Fixed8 gamma
+			gamma := float64(binary.BigEndian.Uint16(tag[12:14])) / 256
+			if gamma <= 0 {
+				return nil, fmt.Errorf("%w: zero gamma", ErrInvalidProfile)
+			}
+			return gammaCurve(gamma), nil
+		case len(tag) >= 12+count*2:
+			table := make(tableCurve, count)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+i*2:])) / 65535
+			}
+			return table, nil
+		}
+
+	case "para":
+		function := int(binary.BigEndian.Uint16(tag[8:10]))
+		paramCounts := []int{1, 3, 4, 5, 7}
+		if function < len(paramCounts) && len(tag) >= 12+paramCounts[function]*4 {
+			curve := parametricCurve{function: function}
+			for i := 0; i < paramCounts[function]; i++ {
+				curve.params[i] = s15Fixed16(tag[12+i*4:])
+			}
+			// The gamma must be positive, and functions 1 and 2 divide by a
+			if curve.params[0] <= 0 {
+				return nil, fmt.Errorf("%w: non-positive gamma %v", ErrInvalidProfile, curve.params[0])
+			}
+			if (function == 1 || function == 2) && curve.params[1] == 0 {
+				return nil, fmt.Errorf("%w: zero slope in parametric curve", ErrInvalidProfile)
+			}
+			return curve, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unsupported tone curve %q", ErrUnsupportedProfile, string(tag[:4]))
+}
+
+// readDescription reads an ASCII textDescriptionType or the first record of a multiLocalizedUnicodeType
+func readDescription(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+
+	switch string(tag[:4]) {
+	case "desc":
+		length := int(binary.BigEndian.Uint32(tag[8:12]))
+		if length > 0 && 12+length <= len(tag) {
+			return string(trimNul(tag[12 : 12+length]))
+		}
+	case "mluc":
+		if len(tag) < 28 {
+			return ""
+		}
+		length := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+length > len(tag) {
+			return ""
+		}
+		// UTF-16BE, plain ASCII names are all this ever needs
+		runes := make([]rune, 0, length/2)
+		for i := offset; i+1 < offset+length; i += 2 {
+			runes = append(runes, rune(binary.BigEndian.Uint16(tag[i:])))
+		}
+		return string(runes)
+	}
+
+	return ""
+}
+
+// trimNul cuts a byte string at its first NUL
+func trimNul(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
+
+// s15Fixed16 decodes a signed 15.16 fixed point number
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// gammaCurve is a pure power function
+type gammaCurve float64
+
+func (g gammaCurve) Linearize(v float64) float64 {
+	return math.Pow(v, float64(g))
+}
+
+// tableCurve is a sampled curve, linearly interpolated between entries
+type tableCurve []float64
+
+func (t tableCurve) Linearize(v float64) float64 {
+	if len(t) == 1 {
+		return t[0]
+	}
+
+	position := v * float64(len(t)-1)
+	index := int(position)
+	if index >= len(t)-1 {
+		return t[len(t)-1]
+	}
+	fraction := position - float64(index)
+	return t[index]*(1-fraction) + t[index+1]*fraction
+}
+
+// parametricCurve implements the five ICC parametric curve functions
+type parametricCurve struct {
+	function int
+	params   [7]float64
+}
+
+func (c parametricCurve) Linearize(x float64) float64 {
+	g, a, b, cc, d, e, f := c.params[0], c.params[1], c.params[2], c.params[3], c.params[4], c.params[5], c.params[6]
+
+	switch c.function {
+	case 0:
+		return math.Pow(x, g)
+	case 1:
+		if x >= -b/a {
+			return math.Pow(a*x+b, g)
+		}
+		return 0
+	case 2:
+		if x >= -b/a {
+			return math.Pow(a*x+b, g) + cc
+		}
+		return cc
+	case 3:
+		if x >= d {
+			return math.Pow(a*x+b, g)
+		}
+		return cc * x
+	default:
+		if x >= d {
+			return math.Pow(a*x+b, g) + e
+		}
+		return cc*x + f
+	}
+}
@@ -0,0 +1,164 @@
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// buildProfile assembles a minimal RGB matrix/TRC profile with sRGB
+// colorants and the same tone curve tag on all three channels
+func buildProfile(curve []byte) []byte {
+	xyz := func(v [3]float64) []byte {
+		tag := make([]byte, 20)
+		copy(tag, "XYZ ")
+		for i, f := range v {
+			binary.BigEndian.PutUint32(tag[8+i*4:], uint32(int32(math.Round(f*65536))))
+		}
+		return tag
+	}
+
+	type tag struct {
+		signature string
+		data      []byte
+	}
+	tags := []tag{
+		{"rXYZ", xyz([3]float64{srgbColorants[0][0], srgbColorants[1][0], srgbColorants[2][0]})},
+		{"gXYZ", xyz([3]float64{srgbColorants[0][1], srgbColorants[1][1], srgbColorants[2][1]})},
+		{"bXYZ", xyz([3]float64{srgbColorants[0][2], srgbColorants[1][2], srgbColorants[2][2]})},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	data := make([]byte, headerSize+4+len(tags)*12)
+	copy(data[16:], "RGB ")
+	copy(data[20:], "XYZ ")
+	copy(data[36:], "acsp")
+	binary.BigEndian.PutUint32(data[headerSize:], uint32(len(tags)))
+	for i, t := range tags {
+		entry := data[headerSize+4+i*12:]
+		copy(entry, t.signature)
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(data)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(t.data)))
+		data = append(data, t.data...)
+	}
+	return data
+}
+
+// paraCurve builds a parametricCurveType tag
+func paraCurve(function int, params ...float64) []byte {
+	tag := make([]byte, 12+len(params)*4)
+	copy(tag, "para")
+	binary.BigEndian.PutUint16(tag[8:], uint16(function))
+	for i, p := range params {
+		binary.BigEndian.PutUint32(tag[12+i*4:], uint32(int32(math.Round(p*65536))))
+	}
+	return tag
+}
+
+// gammaTag builds a single-entry curveType tag with a u8Fixed8 gamma
+func gammaTag(gamma float64) []byte {
+	tag := make([]byte, 14)
+	copy(tag, "curv")
+	binary.BigEndian.PutUint32(tag[8:], 1)
+	binary.BigEndian.PutUint16(tag[12:], uint16(gamma*256))
+	return tag
+}
+
+func TestParseCurves(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve []byte
+		at    float64
+		want  float64
+	}{
+		{"gamma", gammaTag(2.2), 0.5, math.Pow(0.5, 2.2)},
+		{"para function 0", paraCurve(0, 1.8), 0.5, math.Pow(0.5, 1.8)},
+		{"para function 3", paraCurve(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045), 0.5, srgbLinearize(0.5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := Parse(buildProfile(tt.curve))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := profile.Curves[0].Linearize(tt.at); math.Abs(got-tt.want) > 1e-3 {
+				t.Errorf("Linearize(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsDegenerateCurves(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve []byte
+	}{
+		{"zero gamma", gammaTag(0)},
+		{"negative para gamma", paraCurve(0, -2)},
+		{"zero para gamma", paraCurve(0, 0)},
+		{"zero slope", paraCurve(1, 2.2, 0, 0)},
+		{"negative base", paraCurve(3, 2.4, -1, 0, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(buildProfile(tt.curve)); !errors.Is(err, ErrInvalidProfile) {
+				t.Errorf("Parse error = %v, want ErrInvalidProfile", err)
+			}
+		})
+	}
+}
+
+func TestParseRejectsSingularMatrix(t *testing.T) {
+	data := buildProfile(gammaTag(2.2))
+	// Zero the gXYZ tag so the colorant matrix has an empty column
+	for i := 0; i < 3; i++ {
+		entry := data[headerSize+4+1*12:]
+		offset := binary.BigEndian.Uint32(entry[4:])
+		binary.BigEndian.PutUint32(data[int(offset)+8+i*4:], 0)
+	}
+
+	if _, err := Parse(data); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("Parse error = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestEncoderValueClampsNonFinite(t *testing.T) {
+	encode := newSRGBEncoder()
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), -1, 2} {
+		// Must not panic on an out of range index
+		_ = encode.value(v)
+	}
+	if got := encode.value(math.Inf(1)); got != 255 {
+		t.Errorf("value(+Inf) = %d, want 255", got)
+	}
+	if got := encode.value(math.NaN()); got != 0 {
+		t.Errorf("value(NaN) = %d, want 0", got)
+	}
+}
+
+func TestConvertToSRGBKeepsGray(t *testing.T) {
+	profile, err := Parse(buildProfile(gammaTag(1.8)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{128, 128, 128, 255})
+	out := profile.ConvertToSRGB(img)
+
+	// Same primaries, so gray stays gray, only the tone curve changes
+	c := out.NRGBAAt(0, 0)
+	if c.R != c.G || c.G != c.B {
+		t.Errorf("gray became %v", c)
+	}
+	if c.A != 255 {
+		t.Errorf("alpha = %d, want 255", c.A)
+	}
+}
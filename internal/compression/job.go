@@ -2,6 +2,7 @@ package compression
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"time"
 	
@@ -13,18 +14,19 @@ type CompressionJob struct {
 	id        string
 	input     io.Reader
 	format    string
-	quality   int
+	params    CompressionParams
 	algorithm string
 	processor *ImageProcessor
+	data      []byte // input read by EstimateMemory
 }
 
 // NewCompressionJob creates a new compression job
-func NewCompressionJob(id string, input io.Reader, format string, quality int, algorithm string, processor *ImageProcessor) *CompressionJob {
+func NewCompressionJob(id string, input io.Reader, format string, params CompressionParams, algorithm string, processor *ImageProcessor) *CompressionJob {
 	return &CompressionJob{
 		id:        id,
 		input:     input,
 		format:    format,
-		quality:   quality,
+		params:    params,
 		algorithm: algorithm,
 		processor: processor,
 	}
@@ -35,27 +37,46 @@ func (j *CompressionJob) ID() string {
 	return j.id
 }
 
-// Process executes the compression job
-func (j *CompressionJob) Process() (worker.JobResult, error) {
+// EstimateMemory reads the input and estimates the memory the job needs from
+// the image header, so the pool can admit it against its memory budget
+func (j *CompressionJob) EstimateMemory() (int64, error) {
+	inputData, err := j.readInput()
+	if err != nil {
+		return 0, err
+	}
+	return j.processor.EstimateMemory(inputData, j.format, j.params)
+}
+
+// readInput reads the entire input once
+func (j *CompressionJob) readInput() ([]byte, error) {
+	if j.data == nil {
+		data, err := io.ReadAll(j.input)
+		if err != nil {
+			return nil, err
+		}
+		j.data = data
+	}
+	return j.data, nil
+}
+
+// Process executes the compression job, stopping between stages once ctx is done
+func (j *CompressionJob) Process(ctx context.Context) (worker.JobResult, error) {
 	startTime := time.Now()
 	
 	// Read the entire input
-	inputData, err := io.ReadAll(j.input)
+	inputData, err := j.readInput()
 	if err != nil {
 		return nil, err
 	}
 	
-	// Get the algorithm to use
-	var algorithm CompressionAlgorithm
-	if a, ok := j.processor.GetAlgorithm(j.algorithm); ok {
-		algorithm = a
-	} else {
-		// Use default algorithm if requested one isn't available
-		algorithm = j.processor.GetDefaultAlgorithm()
+	// Get the algorithm to use, an unknown name is an error rather than the default
+	algorithm, err := j.processor.ResolveAlgorithm(j.algorithm)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Process the image
-	data, err := j.processor.ProcessImage(bytes.NewReader(inputData), j.format, j.quality, algorithm)
+	encoded, err := j.processor.ProcessImage(ctx, bytes.NewReader(inputData), j.format, j.params, algorithm)
 	if err != nil {
 		return nil, err
 	}
@@ -63,11 +84,17 @@ func (j *CompressionJob) Process() (worker.JobResult, error) {
 	// Create the result
 	result := &CompressionResult{
 		id:           j.id,
-		data:         data,
+		data:         encoded.Data,
+		format:       encoded.Format,
 		jobTime:      time.Since(startTime),
 		algorithmUsed: algorithm.Name(),
 		originalSize:  len(inputData),
-		compressedSize: len(data),
+		compressedSize: len(encoded.Data),
+		qualityUsed:    encoded.Quality,
+		encodePasses:   encoded.Passes,
+		ssim:           encoded.SSIM,
+		psnr:           encoded.PSNR,
+		colorConverted: encoded.ColorConverted,
 	}
 	
 	return result, nil
@@ -77,10 +104,16 @@ func (j *CompressionJob) Process() (worker.JobResult, error) {
 type CompressionResult struct {
 	id            string
 	data          []byte
+	format        string
 	jobTime       time.Duration
 	algorithmUsed string
 	originalSize  int
 	compressedSize int
+	qualityUsed    int
+	encodePasses   int
+	ssim           float64
+	psnr           float64
+	colorConverted bool
 }
 
 // ID returns the job identifier
@@ -93,6 +126,11 @@ func (r *CompressionResult) Data() []byte {
 	return r.data
 }
 
+// Format returns the format the image was encoded to
+func (r *CompressionResult) Format() string {
+	return r.format
+}
+
 // JobTime returns the time taken to process the job
 func (r *CompressionResult) JobTime() time.Duration {
 	return r.jobTime
@@ -113,6 +151,31 @@ func (r *CompressionResult) CompressedSize() int {
 	return r.compressedSize
 }
 
+// QualityUsed returns the encoder quality the output was produced with
+func (r *CompressionResult) QualityUsed() int {
+	return r.qualityUsed
+}
+
+// EncodePasses returns how many times the image was encoded to produce the output
+func (r *CompressionResult) EncodePasses() int {
+	return r.encodePasses
+}
+
+// SSIM returns the structural similarity of the output, 0 if it was not measured
+func (r *CompressionResult) SSIM() float64 {
+	return r.ssim
+}
+
+// PSNR returns the peak signal-to-noise ratio of the output, 0 if it was not measured
+func (r *CompressionResult) PSNR() float64 {
+	return r.psnr
+}
+
+// ColorConverted reports whether the source was converted to sRGB from its ICC profile
+func (r *CompressionResult) ColorConverted() bool {
+	return r.colorConverted
+}
+
 // CompressionRatio returns the compression ratio (compressed/original)
 func (r *CompressionResult) CompressionRatio() float64 {
 	if r.originalSize == 0 {
@@ -0,0 +1,25 @@
+package compression
+
+import (
+	"image"
+	"image/jpeg"
+	"io"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/jpegenc"
+)
+
+// encodeJPEG writes img as JPEG. The standard library encoder handles the
+// default baseline 4:2:0 case; progressive scans, other chroma subsampling
+// and optimized Huffman tables go through jpegenc.
+func encodeJPEG(w io.Writer, img image.Image, params CompressionParams) error {
+	if !params.Progressive && !params.OptimizeHuffman && params.ChromaSubsampling == jpegenc.Subsample420 {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: params.Quality})
+	}
+
+	return jpegenc.Encode(w, img, &jpegenc.Options{
+		Quality:         params.Quality,
+		Progressive:     params.Progressive,
+		Subsampling:     params.ChromaSubsampling,
+		OptimizeHuffman: params.OptimizeHuffman,
+	})
+}
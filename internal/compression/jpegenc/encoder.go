@@ -0,0 +1,354 @@
+// Package jpegenc is a JPEG encoder with the knobs the standard library lacks:
+// progressive scans, a choice of chroma subsampling and optimized Huffman tables
+package jpegenc
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Subsampling is the chroma resolution relative to luma
+type Subsampling int
+
+const (
+	// Subsample420 halves chroma in both directions, like the standard library
+	Subsample420 Subsampling = iota
+	// Subsample422 halves chroma horizontally
+	Subsample422
+	// Subsample444 keeps chroma at full resolution, best for text and line art
+	Subsample444
+)
+
+// ParseSubsampling parses a subsampling name such as 4:2:0 or 444, empty selects 4:2:0
+func ParseSubsampling(s string) (Subsampling, error) {
+	switch strings.ReplaceAll(s, ":", "") {
+	case "", "420":
+		return Subsample420, nil
+	case "422":
+		return Subsample422, nil
+	case "444":
+		return Subsample444, nil
+	default:
+		return Subsample420, fmt.Errorf("unknown chroma subsampling: %s", s)
+	}
+}
+
+// String returns the subsampling in J:a:b notation
+func (s Subsampling) String() string {
+	switch s {
+	case Subsample422:
+		return "4:2:2"
+	case Subsample444:
+		return "4:4:4"
+	default:
+		return "4:2:0"
+	}
+}
+
+// factors returns the luma sampling factors; chroma is always 1x1
+func (s Subsampling) factors() (int, int) {
+	switch s {
+	case Subsample422:
+		return 2, 1
+	case Subsample444:
+		return 1, 1
+	default:
+		return 2, 2
+	}
+}
+
+// Options are the encoding parameters
+type Options struct {
+	// Quality ranges from 1 to 100, higher is better
+	Quality int
+
+	// Progressive writes a progressive JPEG that renders coarse to fine.
+	// Progressive files always use optimized Huffman tables.
+	Progressive bool
+
+	// Subsampling selects the chroma resolution
+	Subsampling Subsampling
+
+	// OptimizeHuffman builds Huffman tables from the image instead of using
+	// the typical tables, which costs a second pass but saves a few percent
+	OptimizeHuffman bool
+}
+
+// DefaultQuality is used when no options are given
+const DefaultQuality = 75
+
+// component is one color channel of the frame
+type component struct {
+	id     byte
+	h, v   int // sampling factors
+	table  int // quantization and Huffman table index: 0 luma, 1 chroma
+	blocks [][64]int32
+
+	// blocksPerLine and blockRows cover whole MCUs; width and height in
+	// blocks cover only the component itself, as non-interleaved scans do
+	blocksPerLine, blockRows  int
+	widthBlocks, heightBlocks int
+}
+
+// encoder holds the frame being written
+type encoder struct {
+	w          *bufio.Writer
+	width      int
+	height     int
+	components []*component
+	hMax, vMax int
+	mcusX      int
+	mcusY      int
+	quant      [2][64]byte
+}
+
+// Encode writes img to w as a JPEG
+func Encode(w io.Writer, img image.Image, o *Options) error {
+	opts := Options{Quality: DefaultQuality}
+	if o != nil {
+		opts = *o
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Dx() >= 1<<16 || bounds.Dy() >= 1<<16 {
+		return fmt.Errorf("jpeg: invalid image size %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	e := &encoder{
+		w:      bufio.NewWriter(w),
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		quant:  scaledQuant(opts.Quality),
+	}
+	e.prepare(img, opts.Subsampling)
+
+	e.writeMarker(0xD8)
+	e.writeDQT()
+	if opts.Progressive {
+		e.writeSOF(0xC2)
+		e.writeProgressiveScans()
+	} else {
+		e.writeSOF(0xC0)
+		e.writeSequentialScan(opts.OptimizeHuffman)
+	}
+	e.writeMarker(0xD9)
+
+	return e.w.Flush()
+}
+
+// prepare converts img to YCbCr planes, subsamples chroma and transforms
+// every block into quantized DCT coefficients
+func (e *encoder) prepare(img image.Image, subsampling Subsampling) {
+	planes, gray := toPlanes(img)
+
+	e.hMax, e.vMax = 1, 1
+	if gray {
+		e.components = []*component{{id: 1, h: 1, v: 1, table: 0}}
+	} else {
+		e.hMax, e.vMax = subsampling.factors()
+		e.components = []*component{
+			{id: 1, h: e.hMax, v: e.vMax, table: 0},
+			{id: 2, h: 1, v: 1, table: 1},
+			{id: 3, h: 1, v: 1, table: 1},
+		}
+	}
+
+	e.mcusX = (e.width + 8*e.hMax - 1) / (8 * e.hMax)
+	e.mcusY = (e.height + 8*e.vMax - 1) / (8 * e.vMax)
+
+	for i, c := range e.components {
+		// Samples of this component, rounded up like a decoder does
+		cw := (e.width*c.h + e.hMax - 1) / e.hMax
+		ch := (e.height*c.v + e.vMax - 1) / e.vMax
+		plane := downsample(planes[i], e.width, e.height, e.hMax/c.h, e.vMax/c.v, cw, ch)
+
+		c.blocksPerLine = e.mcusX * c.h
+		c.blockRows = e.mcusY * c.v
+		c.widthBlocks = (cw + 7) / 8
+		c.heightBlocks = (ch + 7) / 8
+		c.blocks = make([][64]int32, c.blocksPerLine*c.blockRows)
+
+		quant := &e.quant[c.table]
+		var samples [64]float64
+		for by := 0; by < c.blockRows; by++ {
+			for bx := 0; bx < c.blocksPerLine; bx++ {
+				// Blocks past the edge repeat the last row and column
+				for y := 0; y < 8; y++ {
+					sy := min(by*8+y, ch-1)
+					for x := 0; x < 8; x++ {
+						sx := min(bx*8+x, cw-1)
+						samples[y*8+x] = float64(plane[sy*cw+sx]) - 128
+					}
+				}
+				fdct(&samples)
+
+				block := &c.blocks[by*c.blocksPerLine+bx]
+				for k := 0; k < 64; k++ {
+					block[k] = quantize(samples[unzig[k]], quant[k])
+				}
+			}
+		}
+	}
+}
+
+// toPlanes splits img into Y, Cb and Cr planes, or a single Y plane for gray
+// images. Transparent pixels are composited onto black like the standard library.
+func toPlanes(img image.Image) ([][]uint8, bool) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	switch m := img.(type) {
+	case *image.Gray:
+		y := make([]uint8, width*height)
+		for row := 0; row < height; row++ {
+			start := m.PixOffset(bounds.Min.X, bounds.Min.Y+row)
+			copy(y[row*width:], m.Pix[start:start+width])
+		}
+		return [][]uint8{y}, true
+
+	case *image.YCbCr:
+		y := make([]uint8, width*height)
+		cb := make([]uint8, width*height)
+		cr := make([]uint8, width*height)
+		for row := 0; row < height; row++ {
+			for col := 0; col < width; col++ {
+				i := row*width + col
+				y[i] = m.Y[m.YOffset(bounds.Min.X+col, bounds.Min.Y+row)]
+				ci := m.COffset(bounds.Min.X+col, bounds.Min.Y+row)
+				cb[i], cr[i] = m.Cb[ci], m.Cr[ci]
+			}
+		}
+		return [][]uint8{y, cb, cr}, false
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	y := make([]uint8, width*height)
+	cb := make([]uint8, width*height)
+	cr := make([]uint8, width*height)
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			p := row*rgba.Stride + col*4
+			i := row*width + col
+			y[i], cb[i], cr[i] = color.RGBToYCbCr(rgba.Pix[p], rgba.Pix[p+1], rgba.Pix[p+2])
+		}
+	}
+	return [][]uint8{y, cb, cr}, false
+}
+
+// downsample averages fx by fy groups of samples into a cw x ch plane
+func downsample(plane []uint8, width, height, fx, fy, cw, ch int) []uint8 {
+	if fx == 1 && fy == 1 {
+		return plane
+	}
+
+	out := make([]uint8, cw*ch)
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			sum, n := 0, 0
+			for dy := 0; dy < fy; dy++ {
+				sy := y*fy + dy
+				if sy >= height {
+					break
+				}
+				for dx := 0; dx < fx; dx++ {
+					sx := x*fx + dx
+					if sx >= width {
+						break
+					}
+					sum += int(plane[sy*width+sx])
+					n++
+				}
+			}
+			out[y*cw+x] = uint8((sum + n/2) / n)
+		}
+	}
+	return out
+}
+
+// quantize divides a coefficient by its quantizer, rounding to nearest
+func quantize(v float64, q byte) int32 {
+	d := v / float64(q)
+	if d < 0 {
+		return -int32(-d + 0.5)
+	}
+	return int32(d + 0.5)
+}
+
+func (e *encoder) writeMarker(marker byte) {
+	e.w.WriteByte(0xFF)
+	e.w.WriteByte(marker)
+}
+
+// writeSegment writes a marker segment with its length
+func (e *encoder) writeSegment(marker byte, payload []byte) {
+	e.writeMarker(marker)
+	length := len(payload) + 2
+	e.w.WriteByte(byte(length >> 8))
+	e.w.WriteByte(byte(length))
+	e.w.Write(payload)
+}
+
+// writeDQT writes the quantization tables in use
+func (e *encoder) writeDQT() {
+	tables := 2
+	if len(e.components) == 1 {
+		tables = 1
+	}
+
+	var payload []byte
+	for t := 0; t < tables; t++ {
+		payload = append(payload, byte(t))
+		payload = append(payload, e.quant[t][:]...)
+	}
+	e.writeSegment(0xDB, payload)
+}
+
+// writeSOF writes the frame header: 8-bit precision, size and components
+func (e *encoder) writeSOF(marker byte) {
+	payload := []byte{
+		8,
+		byte(e.height >> 8), byte(e.height),
+		byte(e.width >> 8), byte(e.width),
+		byte(len(e.components)),
+	}
+	for _, c := range e.components {
+		payload = append(payload, c.id, byte(c.h<<4|c.v), byte(c.table))
+	}
+	e.writeSegment(marker, payload)
+}
+
+// writeDHT writes the Huffman tables for a scan; class 0 is DC and 1 is AC
+func (e *encoder) writeDHT(specs map[[2]int]huffmanSpec) {
+	var payload []byte
+	for _, key := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		spec, ok := specs[key]
+		if !ok {
+			continue
+		}
+		payload = append(payload, byte(key[0]<<4|key[1]))
+		payload = append(payload, spec.counts[:]...)
+		payload = append(payload, spec.values...)
+	}
+	e.writeSegment(0xC4, payload)
+}
+
+// writeSOS writes a scan header for the given components and spectral band
+func (e *encoder) writeSOS(components []*component, ss, se int) {
+	payload := []byte{byte(len(components))}
+	for _, c := range components {
+		payload = append(payload, c.id, byte(c.table<<4|c.table))
+	}
+	payload = append(payload, byte(ss), byte(se), 0)
+	e.writeSegment(0xDA, payload)
+}
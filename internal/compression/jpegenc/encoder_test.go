@@ -0,0 +1,116 @@
+package jpegenc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gradient builds a smooth color image that survives JPEG with little error.
+// The slope doesn't depend on the size, so small images aren't steeper.
+func gradient(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 3), uint8(y * 3), 128, 255})
+		}
+	}
+	return img
+}
+
+// meanError is the mean absolute difference per channel between two images
+func meanError(a, b image.Image) float64 {
+	bounds := a.Bounds()
+	var total, samples float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ar, ag, ab, _ := a.At(x, y).RGBA()
+			br, bg, bb, _ := b.At(x, y).RGBA()
+			for _, d := range []int{int(ar>>8) - int(br>>8), int(ag>>8) - int(bg>>8), int(ab>>8) - int(bb>>8)} {
+				if d < 0 {
+					d = -d
+				}
+				total += float64(d)
+			}
+			samples += 3
+		}
+	}
+	return total / samples
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	sizes := []image.Point{{1, 1}, {8, 8}, {37, 23}, {64, 17}}
+	subsamplings := []Subsampling{Subsample420, Subsample422, Subsample444}
+
+	for _, size := range sizes {
+		src := gradient(size.X, size.Y)
+		for _, subsampling := range subsamplings {
+			for _, progressive := range []bool{false, true} {
+				for _, optimize := range []bool{false, true} {
+					name := fmt.Sprintf("%dx%d/%v/progressive=%v/optimize=%v", size.X, size.Y, subsampling, progressive, optimize)
+					t.Run(name, func(t *testing.T) {
+						var buf bytes.Buffer
+						opts := &Options{Quality: 90, Progressive: progressive, Subsampling: subsampling, OptimizeHuffman: optimize}
+						if err := Encode(&buf, src, opts); err != nil {
+							t.Fatalf("Encode: %v", err)
+						}
+
+						decoded, err := jpeg.Decode(&buf)
+						if err != nil {
+							t.Fatalf("standard library can't decode the output: %v", err)
+						}
+						if decoded.Bounds().Size() != size {
+							t.Fatalf("decoded size %v, want %v", decoded.Bounds().Size(), size)
+						}
+						if e := meanError(src, decoded); e > 6 {
+							t.Errorf("mean error %.2f per channel", e)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeGrayRoundTrip(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 20, 12))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 2)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, src, &Options{Quality: 90}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("standard library can't decode the output: %v", err)
+	}
+	if e := meanError(src, decoded); e > 6 {
+		t.Errorf("mean error %.2f per channel", e)
+	}
+}
+
+func TestEncodeQualityShrinksOutput(t *testing.T) {
+	src := gradient(64, 64)
+
+	var high, low bytes.Buffer
+	if err := Encode(&high, src, &Options{Quality: 95}); err != nil {
+		t.Fatalf("Encode(95): %v", err)
+	}
+	if err := Encode(&low, src, &Options{Quality: 10}); err != nil {
+		t.Fatalf("Encode(10): %v", err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("quality 10 is %d bytes, not smaller than quality 95's %d", low.Len(), high.Len())
+	}
+}
+
+func TestEncodeRejectsEmptyImage(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewRGBA(image.Rect(0, 0, 0, 5)), nil); err == nil {
+		t.Error("empty image encoded without error")
+	}
+}
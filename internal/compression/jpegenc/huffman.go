@@ -0,0 +1,179 @@
+package jpegenc
+
+import (
+	"bufio"
+	"sort"
+)
+
+// huffmanCode is a compiled table: the code and its length for every symbol
+type huffmanCode struct {
+	code [256]uint16
+	size [256]uint8
+}
+
+// compile assigns canonical codes to the symbols of a spec (Annex C)
+func (s huffmanSpec) compile() *huffmanCode {
+	h := &huffmanCode{}
+	code := uint16(0)
+	k := 0
+	for length := 1; length <= 16; length++ {
+		for i := 0; i < int(s.counts[length-1]); i++ {
+			h.code[s.values[k]] = code
+			h.size[s.values[k]] = uint8(length)
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return h
+}
+
+// optimalSpec builds a Huffman table from symbol frequencies following
+// section K.2 of the spec, with code lengths limited to 16 bits
+func optimalSpec(freq *[256]int64) huffmanSpec {
+	// One reserved symbol keeps any real code from being all ones
+	var counts [257]int64
+	copy(counts[:], freq[:])
+	counts[256] = 1
+
+	// A table needs at least one real symbol
+	empty := true
+	for _, f := range freq {
+		if f > 0 {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		counts[0] = 1
+	}
+
+	var codeSize [257]int
+	var others [257]int
+	for i := range others {
+		others[i] = -1
+	}
+
+	for {
+		// Find the two least frequent symbols, preferring higher indexes on ties
+		c1, c2 := -1, -1
+		for i, f := range counts {
+			if f > 0 && (c1 < 0 || f <= counts[c1]) {
+				c1 = i
+			}
+		}
+		for i, f := range counts {
+			if f > 0 && i != c1 && (c2 < 0 || f <= counts[c2]) {
+				c2 = i
+			}
+		}
+		if c2 < 0 {
+			break
+		}
+
+		counts[c1] += counts[c2]
+		counts[c2] = 0
+
+		codeSize[c1]++
+		for others[c1] >= 0 {
+			c1 = others[c1]
+			codeSize[c1]++
+		}
+		others[c1] = c2
+
+		codeSize[c2]++
+		for others[c2] >= 0 {
+			c2 = others[c2]
+			codeSize[c2]++
+		}
+	}
+
+	var bits [33]int
+	for _, size := range codeSize {
+		if size > 0 {
+			bits[size]++
+		}
+	}
+
+	// Move codes longer than 16 bits up the tree
+	for i := 32; i > 16; i-- {
+		for bits[i] > 0 {
+			j := i - 2
+			for bits[j] == 0 {
+				j--
+			}
+			bits[i] -= 2
+			bits[i-1]++
+			bits[j+1] += 2
+			bits[j]--
+		}
+	}
+
+	// Drop the reserved symbol from the longest length
+	i := 16
+	for bits[i] == 0 {
+		i--
+	}
+	bits[i]--
+
+	var spec huffmanSpec
+	for length := 1; length <= 16; length++ {
+		spec.counts[length-1] = byte(bits[length])
+	}
+
+	// Symbols ordered by code length, then by value
+	type symbolSize struct {
+		symbol int
+		size   int
+	}
+	var symbols []symbolSize
+	for symbol := 0; symbol < 256; symbol++ {
+		if codeSize[symbol] > 0 {
+			symbols = append(symbols, symbolSize{symbol, codeSize[symbol]})
+		}
+	}
+	sort.SliceStable(symbols, func(a, b int) bool {
+		return symbols[a].size < symbols[b].size
+	})
+	for _, s := range symbols {
+		spec.values = append(spec.values, byte(s.symbol))
+	}
+
+	return spec
+}
+
+// bitWriter packs entropy-coded bits into bytes, stuffing a zero after every 0xFF
+type bitWriter struct {
+	w     *bufio.Writer
+	bits  uint32
+	nBits uint
+}
+
+// write emits the low n bits of v, most significant first
+func (b *bitWriter) write(v uint32, n uint) {
+	for n > 0 {
+		take := n
+		if take > 8 {
+			take = 8
+		}
+		n -= take
+		b.bits = b.bits<<take | (v>>n)&(1<<take-1)
+		b.nBits += take
+		for b.nBits >= 8 {
+			b.nBits -= 8
+			c := byte(b.bits >> b.nBits)
+			b.w.WriteByte(c)
+			if c == 0xFF {
+				b.w.WriteByte(0)
+			}
+		}
+		b.bits &= 1<<b.nBits - 1
+	}
+}
+
+// flush pads the last byte with ones
+func (b *bitWriter) flush() {
+	if b.nBits > 0 {
+		b.write(1<<(8-b.nBits)-1, 8-b.nBits)
+	}
+}
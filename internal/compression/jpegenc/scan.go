@@ -0,0 +1,252 @@
+package jpegenc
+
+import (
+	"math"
+	"math/bits"
+)
+
+// dctCos holds C(u)/2 * cos((2x+1)uπ/16) for the separable forward DCT
+var dctCos = func() (t [8][8]float64) {
+	for u := 0; u < 8; u++ {
+		scale := 0.5
+		if u == 0 {
+			scale = 0.5 / math.Sqrt2
+		}
+		for x := 0; x < 8; x++ {
+			t[u][x] = scale * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return t
+}()
+
+// fdct transforms a block of level-shifted samples in place, rows then columns
+func fdct(b *[64]float64) {
+	var tmp [64]float64
+	for y := 0; y < 8; y++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for x := 0; x < 8; x++ {
+				sum += dctCos[u][x] * b[y*8+x]
+			}
+			tmp[y*8+u] = sum
+		}
+	}
+	for u := 0; u < 8; u++ {
+		for v := 0; v < 8; v++ {
+			sum := 0.0
+			for y := 0; y < 8; y++ {
+				sum += dctCos[v][y] * tmp[y*8+u]
+			}
+			b[v*8+u] = sum
+		}
+	}
+}
+
+// entropyCoder either writes a scan or, with no writer, counts the symbols it
+// would write so optimal tables can be built for it
+type entropyCoder struct {
+	bw       *bitWriter
+	dc, ac   [2]*huffmanCode
+	dcFreq   [2][256]int64
+	acFreq   [2][256]int64
+	eobRun   int
+	eobTable int
+}
+
+func (c *entropyCoder) symbol(ac bool, table int, s byte) {
+	if c.bw == nil {
+		if ac {
+			c.acFreq[table][s]++
+		} else {
+			c.dcFreq[table][s]++
+		}
+		return
+	}
+
+	h := c.dc[table]
+	if ac {
+		h = c.ac[table]
+	}
+	c.bw.write(uint32(h.code[s]), uint(h.size[s]))
+}
+
+func (c *entropyCoder) bits(v uint32, n uint) {
+	if c.bw != nil && n > 0 {
+		c.bw.write(v, n)
+	}
+}
+
+// magnitude returns the size category of v and the bits that encode it
+func magnitude(v int32) (uint, uint32) {
+	if v < 0 {
+		n := uint(bits.Len32(uint32(-v)))
+		return n, uint32(v-1) & (1<<n - 1)
+	}
+	return uint(bits.Len32(uint32(v))), uint32(v)
+}
+
+// encodeDC writes the difference from the previous DC of the component
+func (c *entropyCoder) encodeDC(table int, diff int32) {
+	n, v := magnitude(diff)
+	c.symbol(false, table, byte(n))
+	c.bits(v, n)
+}
+
+// encodeAC writes coefficients ss to se of a block, run-length coding zeros.
+// With eobRuns set, trailing zeros extend an end-of-band run shared across
+// blocks as progressive scans allow; otherwise each block ends with EOB.
+func (c *entropyCoder) encodeAC(table int, block *[64]int32, ss, se int, eobRuns bool) {
+	run := 0
+	for k := ss; k <= se; k++ {
+		coef := block[k]
+		if coef == 0 {
+			run++
+			continue
+		}
+
+		c.flushEOBRun()
+		for run > 15 {
+			c.symbol(true, table, 0xF0)
+			run -= 16
+		}
+		n, v := magnitude(coef)
+		c.symbol(true, table, byte(run<<4)|byte(n))
+		c.bits(v, n)
+		run = 0
+	}
+
+	if run == 0 {
+		return
+	}
+	if !eobRuns {
+		c.symbol(true, table, 0x00)
+		return
+	}
+
+	c.eobTable = table
+	c.eobRun++
+	if c.eobRun == 0x7FFF {
+		c.flushEOBRun()
+	}
+}
+
+// flushEOBRun writes the pending end-of-band run, if any
+func (c *entropyCoder) flushEOBRun() {
+	if c.eobRun == 0 {
+		return
+	}
+	n := uint(bits.Len(uint(c.eobRun))) - 1
+	c.symbol(true, c.eobTable, byte(n<<4))
+	c.bits(uint32(c.eobRun), n)
+	c.eobRun = 0
+}
+
+// forEachBlock visits the blocks of a scan in coding order. Scans of several
+// components interleave them MCU by MCU; a single component is coded on its
+// own grid, which stops at the component edge instead of the MCU edge.
+func (e *encoder) forEachBlock(components []*component, fn func(i int, c *component, block *[64]int32)) {
+	if len(components) == 1 {
+		c := components[0]
+		for by := 0; by < c.heightBlocks; by++ {
+			for bx := 0; bx < c.widthBlocks; bx++ {
+				fn(0, c, &c.blocks[by*c.blocksPerLine+bx])
+			}
+		}
+		return
+	}
+
+	for my := 0; my < e.mcusY; my++ {
+		for mx := 0; mx < e.mcusX; mx++ {
+			for i, c := range components {
+				for v := 0; v < c.v; v++ {
+					for h := 0; h < c.h; h++ {
+						by, bx := my*c.v+v, mx*c.h+h
+						fn(i, c, &c.blocks[by*c.blocksPerLine+bx])
+					}
+				}
+			}
+		}
+	}
+}
+
+// codeScan runs one scan of the given band through the coder
+func (e *encoder) codeScan(coder *entropyCoder, components []*component, ss, se int, progressive bool) {
+	prevDC := make([]int32, len(components))
+	e.forEachBlock(components, func(i int, c *component, block *[64]int32) {
+		if ss == 0 {
+			coder.encodeDC(c.table, block[0]-prevDC[i])
+			prevDC[i] = block[0]
+		}
+		if se > 0 {
+			coder.encodeAC(c.table, block, max(ss, 1), se, progressive)
+		}
+	})
+	coder.flushEOBRun()
+}
+
+// tablesUsed returns the table indexes used by the components
+func tablesUsed(components []*component) []int {
+	if len(components) == 1 || components[0].table == components[len(components)-1].table {
+		return []int{components[0].table}
+	}
+	return []int{0, 1}
+}
+
+// writeScan writes the DHT, SOS and entropy-coded data of one scan. With
+// optimize set, the scan is counted first and coded with tables built for it;
+// otherwise the typical tables are used.
+func (e *encoder) writeScan(components []*component, ss, se int, progressive, optimize bool) {
+	specs := make(map[[2]int]huffmanSpec)
+	tables := tablesUsed(components)
+
+	if optimize {
+		counter := &entropyCoder{}
+		e.codeScan(counter, components, ss, se, progressive)
+		for _, t := range tables {
+			if ss == 0 {
+				specs[[2]int{0, t}] = optimalSpec(&counter.dcFreq[t])
+			}
+			if se > 0 {
+				specs[[2]int{1, t}] = optimalSpec(&counter.acFreq[t])
+			}
+		}
+	} else {
+		for _, t := range tables {
+			specs[[2]int{0, t}] = standardDC[t]
+			specs[[2]int{1, t}] = standardAC[t]
+		}
+	}
+
+	coder := &entropyCoder{bw: &bitWriter{w: e.w}}
+	for key, spec := range specs {
+		if key[0] == 0 {
+			coder.dc[key[1]] = spec.compile()
+		} else {
+			coder.ac[key[1]] = spec.compile()
+		}
+	}
+
+	e.writeDHT(specs)
+	e.writeSOS(components, ss, se)
+	e.codeScan(coder, components, ss, se, progressive)
+	coder.bw.flush()
+}
+
+// writeSequentialScan writes a baseline frame as a single interleaved scan
+func (e *encoder) writeSequentialScan(optimize bool) {
+	e.writeScan(e.components, 0, 63, false, optimize)
+}
+
+// writeProgressiveScans writes the DC of every component first, then the low
+// luma frequencies, the chroma and finally the remaining luma detail, which is
+// the spectral selection order libjpeg uses
+func (e *encoder) writeProgressiveScans() {
+	e.writeScan(e.components, 0, 0, true, true)
+
+	y := e.components[:1]
+	e.writeScan(y, 1, 5, true, true)
+	for _, c := range e.components[1:] {
+		e.writeScan([]*component{c}, 1, 63, true, true)
+	}
+	e.writeScan(y, 6, 63, true, true)
+}
@@ -0,0 +1,90 @@
+package compression
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Kernel names the resampling filter used when scaling
+type Kernel string
+
+const (
+	// KernelNearest picks the closest source pixel, fastest and blockiest
+	KernelNearest Kernel = "nearest"
+
+	// KernelApproxBilinear is the fast bilinear approximation used by default
+	KernelApproxBilinear Kernel = "approx-bilinear"
+
+	// KernelBilinear is exact bilinear filtering over the full footprint
+	KernelBilinear Kernel = "bilinear"
+
+	// KernelCatmullRom is a sharp bicubic filter
+	KernelCatmullRom Kernel = "catmullrom"
+
+	// KernelLanczos is a three-lobe Lanczos filter, the sharpest for large reductions
+	KernelLanczos Kernel = "lanczos"
+)
+
+// DefaultKernel is used when a request does not pick a kernel
+const DefaultKernel = KernelApproxBilinear
+
+// lanczos3 is a windowed sinc filter with a support of three pixels
+var lanczos3 = &draw.Kernel{
+	Support: 3,
+	At: func(t float64) float64 {
+		if t == 0 {
+			return 1
+		}
+		if t < 0 {
+			t = -t
+		}
+		if t >= 3 {
+			return 0
+		}
+		piT := math.Pi * t
+		return 3 * math.Sin(piT) * math.Sin(piT/3) / (piT * piT)
+	},
+}
+
+// ParseKernel validates a kernel name, the empty string selects the default
+func ParseKernel(value string) (Kernel, error) {
+	switch kernel := Kernel(strings.ToLower(value)); kernel {
+	case "":
+		return DefaultKernel, nil
+	case "catmull-rom", "bicubic":
+		return KernelCatmullRom, nil
+	case "lanczos3":
+		return KernelLanczos, nil
+	case KernelNearest, KernelApproxBilinear, KernelBilinear, KernelCatmullRom, KernelLanczos:
+		return kernel, nil
+	default:
+		return "", fmt.Errorf("unknown kernel: %s", value)
+	}
+}
+
+// Name returns the kernel name used in metrics, resolving the default
+func (k Kernel) Name() string {
+	if k == "" {
+		return string(DefaultKernel)
+	}
+	return string(k)
+}
+
+// interpolator returns the draw scaler implementing the kernel
+func (k Kernel) interpolator() draw.Interpolator {
+	switch k {
+	case KernelNearest:
+		return draw.NearestNeighbor
+	case KernelBilinear:
+		return draw.BiLinear
+	case KernelCatmullRom:
+		return draw.CatmullRom
+	case KernelLanczos:
+		return lanczos3
+	default:
+		return draw.ApproxBiLinear
+	}
+}
@@ -0,0 +1,50 @@
+package compression
+
+import (
+	"context"
+	"image"
+)
+
+// LegacyScaleAlgorithm keeps the original coupled behavior where quality also
+// shrinks the dimensions, so quality 50 halves the image before encoding at 50
+type LegacyScaleAlgorithm struct{}
+
+func NewLegacyScaleAlgorithm() *LegacyScaleAlgorithm {
+	return &LegacyScaleAlgorithm{}
+}
+
+func (a *LegacyScaleAlgorithm) Name() string {
+	return "legacy"
+}
+
+func (a *LegacyScaleAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// If quality is 100, return the original image
+	if params.Quality == 100 {
+		return img, nil
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Calculate new dimensions based on quality
+	scaleFactor := float64(params.Quality) / 100.0
+	newWidth := int(float64(width) * scaleFactor)
+	newHeight := int(float64(height) * scaleFactor)
+
+	// Ensure minimum dimensions
+	if newWidth < 10 {
+		newWidth = 10
+	}
+	if newHeight < 10 {
+		newHeight = 10
+	}
+
+	newImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	scaleInto(newImg, newImg.Bounds(), img, bounds, params.Kernel)
+
+	return newImg, nil
+}
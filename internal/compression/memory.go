@@ -0,0 +1,86 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// memoryOverhead counts the decoded image and the working copy made while
+// resizing or converting it
+const memoryOverhead = 2
+
+// ErrImageTooLarge is returned for images over the processor's pixel limit
+var ErrImageTooLarge = errors.New("image too large")
+
+// SetMaxPixels sets the largest width times height the processor decodes;
+// zero or less removes the limit
+func (p *ImageProcessor) SetMaxPixels(maxPixels int64) {
+	p.maxPixels = maxPixels
+}
+
+// EstimateMemory reads only the image headers and estimates the bytes needed
+// to decode and process the image into format. Images over the pixel limit
+// are rejected before any pixel data is decoded, which also stops
+// decompression bombs. The estimate covers every frame of an animated GIF,
+// the selected page of a TIFF, the resize output of each frame and the
+// candidate decodes FormatBest scores.
+func (p *ImageProcessor) EstimateMemory(data []byte, format string, params CompressionParams) (int64, error) {
+	var config image.Config
+	var err error
+	if isTIFF(data) {
+		// DecodeConfig only sees the first page
+		config, err = decodeTIFFPageConfig(data, params.Page)
+	} else {
+		config, _, err = image.DecodeConfig(bytes.NewReader(data))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error decoding image header: %v", err)
+	}
+
+	pixels := int64(config.Width) * int64(config.Height)
+	if p.maxPixels > 0 && pixels > p.maxPixels {
+		return 0, fmt.Errorf("%w: %dx%d is over the %d pixel limit",
+			ErrImageTooLarge, config.Width, config.Height, p.maxPixels)
+	}
+
+	frames := int64(1)
+	decoded := pixels * bytesPerPixel(config.ColorModel) * memoryOverhead
+	if isGIF(data) {
+		if count, framePixels := scanGIF(data); count > 1 {
+			if p.maxPixels > 0 && framePixels > p.maxPixels {
+				return 0, fmt.Errorf("%w: gif frames decode to %d pixels, over the %d pixel limit",
+					ErrImageTooLarge, framePixels, p.maxPixels)
+			}
+			// Paletted frames from the decoder, a composited RGBA copy of
+			// each and the canvas they are played out on
+			frames = int64(count)
+			decoded = framePixels*(1+4) + pixels*4
+		}
+	}
+
+	// Every frame gets its own RGBA resize output
+	outputWidth, outputHeight := outputDimensions(config.Width, config.Height, params)
+	output := int64(outputWidth) * int64(outputHeight) * 4
+	estimate := decoded + frames*output
+
+	// FormatBest decodes each still candidate back to score it
+	if format == FormatBest && frames == 1 {
+		estimate += int64(len(bestCandidates)) * output
+	}
+
+	return estimate, nil
+}
+
+// bytesPerPixel is the decoded size of one pixel. Everything but 16-bit
+// images is counted as 8-bit RGBA, which processing converts to anyway.
+func bytesPerPixel(model color.Model) int64 {
+	switch model {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return 8
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,103 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// encodeTestPNG builds an opaque RGBA PNG of the given size
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encoding test png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEstimateMemory(t *testing.T) {
+	processor := NewImageProcessor()
+	data := encodeTestPNG(t, 100, 50)
+
+	// Decode and working copy, plus a same-size output
+	plain := int64(100*50*4*memoryOverhead + 100*50*4)
+
+	tests := []struct {
+		name   string
+		format string
+		params CompressionParams
+		want   int64
+	}{
+		{"no resize", "webp", CompressionParams{}, plain},
+		{"downscale", "webp", CompressionParams{Width: 50}, 100*50*4*memoryOverhead + 50*25*4},
+		{"upscale", "webp", CompressionParams{Scale: 4}, 100*50*4*memoryOverhead + 400*200*4},
+		{"outside box", "webp", CompressionParams{Width: 10, Height: 100, Fit: FitOutside}, 100*50*4*memoryOverhead + 200*100*4},
+		{"best candidates", FormatBest, CompressionParams{}, plain + int64(len(bestCandidates))*100*50*4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := processor.EstimateMemory(data, tt.format, tt.params)
+			if err != nil {
+				t.Fatalf("EstimateMemory: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EstimateMemory = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateMemoryPixelLimit(t *testing.T) {
+	processor := NewImageProcessor()
+	processor.SetMaxPixels(100 * 50)
+
+	if _, err := processor.EstimateMemory(encodeTestPNG(t, 100, 50), "webp", CompressionParams{}); err != nil {
+		t.Errorf("image at the limit: %v", err)
+	}
+	if _, err := processor.EstimateMemory(encodeTestPNG(t, 101, 50), "webp", CompressionParams{}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("image over the limit: err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestEstimateMemoryCountsGIFFrames(t *testing.T) {
+	processor := NewImageProcessor()
+	data := encodeTestGIF(t, 10, 40, 30)
+
+	got, err := processor.EstimateMemory(data, "webp", CompressionParams{})
+	if err != nil {
+		t.Fatalf("EstimateMemory: %v", err)
+	}
+	canvas := int64(40 * 30)
+	if want := 10*canvas*5 + canvas*4 + 10*canvas*4; got != want {
+		t.Errorf("EstimateMemory = %d, want %d", got, want)
+	}
+
+	// One frame fits the limit, all ten do not
+	processor.SetMaxPixels(5 * canvas)
+	if _, err := processor.EstimateMemory(data, "webp", CompressionParams{}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestEstimateMemoryChecksTIFFPage(t *testing.T) {
+	processor := NewImageProcessor()
+
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 20, 10)), nil); err != nil {
+		t.Fatalf("encoding test tiff: %v", err)
+	}
+
+	if _, err := processor.EstimateMemory(buf.Bytes(), "webp", CompressionParams{Page: 0}); err != nil {
+		t.Errorf("page 0: %v", err)
+	}
+	if _, err := processor.EstimateMemory(buf.Bytes(), "webp", CompressionParams{Page: 1}); err == nil {
+		t.Error("page 1 of a single-page tiff was accepted")
+	}
+}
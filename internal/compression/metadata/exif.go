@@ -0,0 +1,170 @@
+package metadata
+
+import (
+	"encoding/binary"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagArtist      = 0x013B
+	tagCopyright   = 0x8298
+
+	typeASCII = 2
+	typeShort = 3
+)
+
+// ifdEntry is a single IFD0 field with its value bytes resolved
+type ifdEntry struct {
+	tag       uint16
+	fieldType uint16
+	count     uint32
+	value     []byte
+}
+
+// typeSizes maps TIFF field types to the size of one value
+var typeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiffByteOrder returns the byte order from a TIFF header, or nil if invalid
+func tiffByteOrder(tiff []byte) binary.ByteOrder {
+	if len(tiff) < 8 {
+		return nil
+	}
+
+	switch string(tiff[:2]) {
+	case "II":
+		return binary.LittleEndian
+	case "MM":
+		return binary.BigEndian
+	default:
+		return nil
+	}
+}
+
+// findIFD0Entry returns the offset of the IFD0 entry for tag, or -1
+func findIFD0Entry(tiff []byte, order binary.ByteOrder, tag uint16) int {
+	ifdOffset := int(order.Uint32(tiff[4:8]))
+	if ifdOffset < 8 || ifdOffset+2 > len(tiff) {
+		return -1
+	}
+
+	entryCount := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for i := 0; i < entryCount; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return -1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == tag {
+			return entry
+		}
+	}
+
+	return -1
+}
+
+// readIFD0Entry reads the tag from IFD0 along with its value bytes
+func readIFD0Entry(tiff []byte, tag uint16) (ifdEntry, bool) {
+	order := tiffByteOrder(tiff)
+	if order == nil {
+		return ifdEntry{}, false
+	}
+
+	entry := findIFD0Entry(tiff, order, tag)
+	if entry < 0 {
+		return ifdEntry{}, false
+	}
+
+	fieldType := order.Uint16(tiff[entry+2 : entry+4])
+	count := order.Uint32(tiff[entry+4 : entry+8])
+	size, ok := typeSizes[fieldType]
+	if !ok {
+		return ifdEntry{}, false
+	}
+
+	// Values of up to four bytes are stored inline, larger ones at an offset
+	length := int(count) * size
+	start := entry + 8
+	if length > 4 {
+		start = int(order.Uint32(tiff[entry+8 : entry+12]))
+	}
+	if length < 0 || start+length > len(tiff) {
+		return ifdEntry{}, false
+	}
+
+	return ifdEntry{
+		tag:       tag,
+		fieldType: fieldType,
+		count:     count,
+		value:     tiff[start : start+length],
+	}, true
+}
+
+// readOrientation reads the orientation tag from EXIF data, 0 if absent
+func readOrientation(tiff []byte) int {
+	entry, ok := readIFD0Entry(tiff, tagOrientation)
+	if !ok || entry.fieldType != typeShort || len(entry.value) < 2 {
+		return 0
+	}
+	return int(tiffByteOrder(tiff).Uint16(entry.value))
+}
+
+// setOrientation returns a copy of the EXIF data with the orientation replaced
+func setOrientation(tiff []byte, orientation uint16) []byte {
+	order := tiffByteOrder(tiff)
+	if order == nil {
+		return tiff
+	}
+
+	entry := findIFD0Entry(tiff, order, tagOrientation)
+	if entry < 0 || order.Uint16(tiff[entry+2:entry+4]) != typeShort {
+		return tiff
+	}
+
+	updated := append([]byte(nil), tiff...)
+	order.PutUint16(updated[entry+8:entry+10], orientation)
+	return updated
+}
+
+// copyrightOnly builds a minimal EXIF block holding just the Artist and
+// Copyright tags of the source, or nil when neither is present
+func copyrightOnly(tiff []byte) []byte {
+	var entries []ifdEntry
+	for _, tag := range []uint16{tagArtist, tagCopyright} {
+		if entry, ok := readIFD0Entry(tiff, tag); ok && entry.fieldType == typeASCII {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Header, then IFD0 with its entries and a zero next-IFD offset, then values
+	order := binary.BigEndian
+	ifdSize := 2 + len(entries)*12 + 4
+	out := make([]byte, 8+ifdSize)
+	copy(out, "MM\x00\x2a")
+	order.PutUint32(out[4:8], 8)
+	order.PutUint16(out[8:10], uint16(len(entries)))
+
+	for i, entry := range entries {
+		field := out[10+i*12 : 22+i*12]
+		order.PutUint16(field[0:2], entry.tag)
+		order.PutUint16(field[2:4], entry.fieldType)
+		order.PutUint32(field[4:8], entry.count)
+
+		if len(entry.value) <= 4 {
+			copy(field[8:12], entry.value)
+			continue
+		}
+		order.PutUint32(field[8:12], uint32(len(out)))
+		out = append(out, entry.value...)
+
+		// Keep value offsets word aligned
+		if len(out)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,158 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	markerAPP1 = 0xE1
+	markerAPP2 = 0xE2
+	markerSOS  = 0xDA
+	markerEOI  = 0xD9
+
+	// maxSegmentPayload is the largest payload a JPEG marker segment can hold
+	maxSegmentPayload = 0xFFFF - 2
+)
+
+var (
+	jpegSOI       = []byte{0xFF, 0xD8}
+	jpegExifID    = []byte("Exif\x00\x00")
+	jpegXMPID     = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	jpegICCID     = []byte("ICC_PROFILE\x00")
+	iccChunkLimit = maxSegmentPayload - len(jpegICCID) - 2
+)
+
+// jpegSegment is a marker segment before the scan data
+type jpegSegment struct {
+	marker  byte
+	payload []byte
+}
+
+// readJPEGSegments walks the marker segments up to the first scan
+func readJPEGSegments(data []byte) []jpegSegment {
+	var segments []jpegSegment
+
+	pos := len(jpegSOI)
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			break
+		}
+		marker := data[pos+1]
+
+		// Fill bytes may pad between segments
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+
+		// Standalone markers carry no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+
+		// Metadata always precedes the scan data
+		if marker == markerSOS || marker == markerEOI {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+
+		segments = append(segments, jpegSegment{
+			marker:  marker,
+			payload: data[pos+4 : pos+2+length],
+		})
+		pos += 2 + length
+	}
+
+	return segments
+}
+
+// extractJPEG collects EXIF and XMP from APP1 and the ICC profile from APP2
+func extractJPEG(data []byte) *Metadata {
+	m := &Metadata{}
+
+	type iccChunk struct {
+		sequence int
+		data     []byte
+	}
+	var iccChunks []iccChunk
+
+	for _, segment := range readJPEGSegments(data) {
+		switch {
+		case segment.marker == markerAPP1 && bytes.HasPrefix(segment.payload, jpegExifID):
+			if m.EXIF == nil {
+				m.EXIF = segment.payload[len(jpegExifID):]
+			}
+		case segment.marker == markerAPP1 && bytes.HasPrefix(segment.payload, jpegXMPID):
+			if m.XMP == nil {
+				m.XMP = segment.payload[len(jpegXMPID):]
+			}
+		case segment.marker == markerAPP2 && bytes.HasPrefix(segment.payload, jpegICCID):
+			// Profiles are split into numbered chunks: sequence number, then total count
+			rest := segment.payload[len(jpegICCID):]
+			if len(rest) > 2 {
+				iccChunks = append(iccChunks, iccChunk{sequence: int(rest[0]), data: rest[2:]})
+			}
+		}
+	}
+
+	if len(iccChunks) > 0 {
+		sort.SliceStable(iccChunks, func(i, j int) bool {
+			return iccChunks[i].sequence < iccChunks[j].sequence
+		})
+		for _, chunk := range iccChunks {
+			m.ICC = append(m.ICC, chunk.data...)
+		}
+	}
+
+	return m
+}
+
+// injectJPEG inserts APP1/APP2 segments right after the SOI marker.
+// Blocks too large for a single segment are dropped, except ICC which is chunked.
+func injectJPEG(data []byte, m *Metadata) ([]byte, error) {
+	if !bytes.HasPrefix(data, jpegSOI) {
+		return data, nil
+	}
+
+	var segments bytes.Buffer
+	if len(m.EXIF) > 0 && len(jpegExifID)+len(m.EXIF) <= maxSegmentPayload {
+		writeJPEGSegment(&segments, markerAPP1, jpegExifID, m.EXIF)
+	}
+	if len(m.XMP) > 0 && len(jpegXMPID)+len(m.XMP) <= maxSegmentPayload {
+		writeJPEGSegment(&segments, markerAPP1, jpegXMPID, m.XMP)
+	}
+	if len(m.ICC) > 0 {
+		total := (len(m.ICC) + iccChunkLimit - 1) / iccChunkLimit
+		if total <= 255 {
+			for i := 0; i < total; i++ {
+				end := (i + 1) * iccChunkLimit
+				if end > len(m.ICC) {
+					end = len(m.ICC)
+				}
+				header := append(append([]byte(nil), jpegICCID...), byte(i+1), byte(total))
+				writeJPEGSegment(&segments, markerAPP2, header, m.ICC[i*iccChunkLimit:end])
+			}
+		}
+	}
+
+	out := make([]byte, 0, len(data)+segments.Len())
+	out = append(out, jpegSOI...)
+	out = append(out, segments.Bytes()...)
+	out = append(out, data[len(jpegSOI):]...)
+	return out, nil
+}
+
+// writeJPEGSegment writes a marker segment made of an identifier and a payload
+func writeJPEGSegment(buf *bytes.Buffer, marker byte, id []byte, payload []byte) {
+	length := 2 + len(id) + len(payload)
+	buf.Write([]byte{0xFF, marker, byte(length >> 8), byte(length)})
+	buf.Write(id)
+	buf.Write(payload)
+}
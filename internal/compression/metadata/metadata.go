@@ -0,0 +1,145 @@
+// Package metadata extracts EXIF, ICC and XMP metadata from encoded images
+// and copies the parts a policy allows into freshly encoded output
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/riff"
+)
+
+// Policy decides which metadata survives compression
+type Policy string
+
+const (
+	// PolicyStrip drops all metadata, the default for privacy
+	PolicyStrip Policy = "strip"
+
+	// PolicyKeep copies EXIF, ICC and XMP unchanged
+	PolicyKeep Policy = "keep"
+
+	// PolicyICC only keeps the color profile
+	PolicyICC Policy = "icc"
+
+	// PolicyCopyright only keeps the EXIF Artist and Copyright tags
+	PolicyCopyright Policy = "copyright"
+)
+
+// ParsePolicy validates a policy name, the empty string selects PolicyStrip
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(value)); policy {
+	case "", "strip-all", "none":
+		return PolicyStrip, nil
+	case "keep-all", "all":
+		return PolicyKeep, nil
+	case "keep-icc":
+		return PolicyICC, nil
+	case "keep-copyright":
+		return PolicyCopyright, nil
+	case PolicyStrip, PolicyKeep, PolicyICC, PolicyCopyright:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown metadata policy: %s", value)
+	}
+}
+
+// Metadata holds the metadata blocks that can be carried between formats
+type Metadata struct {
+	// EXIF is TIFF-structured data without the JPEG "Exif\0\0" prefix
+	EXIF []byte
+
+	// ICC is a complete ICC color profile
+	ICC []byte
+
+	// XMP is the raw XMP packet
+	XMP []byte
+}
+
+// Extract reads the metadata of a JPEG, PNG or WebP image.
+// Other formats and malformed data yield empty metadata.
+func Extract(data []byte) *Metadata {
+	switch {
+	case bytes.HasPrefix(data, jpegSOI):
+		return extractJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return extractPNG(data)
+	case riff.IsWebP(data):
+		return extractWebP(data)
+	default:
+		return &Metadata{}
+	}
+}
+
+// Empty reports whether there is no metadata at all
+func (m *Metadata) Empty() bool {
+	return m == nil || (len(m.EXIF) == 0 && len(m.ICC) == 0 && len(m.XMP) == 0)
+}
+
+// Size returns the number of metadata bytes, before container overhead
+func (m *Metadata) Size() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.EXIF) + len(m.ICC) + len(m.XMP)
+}
+
+// Orientation returns the EXIF orientation (1-8), or 1 when unknown
+func (m *Metadata) Orientation() int {
+	if m == nil {
+		return 1
+	}
+
+	orientation := readOrientation(m.EXIF)
+	if orientation < 1 || orientation > 8 {
+		return 1
+	}
+	return orientation
+}
+
+// Filter returns the metadata allowed by policy
+func (m *Metadata) Filter(policy Policy) *Metadata {
+	if m == nil {
+		return &Metadata{}
+	}
+
+	switch policy {
+	case PolicyKeep:
+		return &Metadata{EXIF: m.EXIF, ICC: m.ICC, XMP: m.XMP}
+	case PolicyICC:
+		return &Metadata{ICC: m.ICC}
+	case PolicyCopyright:
+		return &Metadata{EXIF: copyrightOnly(m.EXIF)}
+	default:
+		return &Metadata{}
+	}
+}
+
+// ResetOrientation marks the EXIF orientation as upright, for pixels that
+// were already rotated. The EXIF block is copied, not modified in place.
+func (m *Metadata) ResetOrientation() {
+	if m == nil || len(m.EXIF) == 0 {
+		return
+	}
+	m.EXIF = setOrientation(m.EXIF, 1)
+}
+
+// Inject writes the metadata into encoded image data of the given format.
+// Formats without metadata support are returned unchanged.
+func Inject(data []byte, format string, m *Metadata) ([]byte, error) {
+	if m.Empty() {
+		return data, nil
+	}
+
+	switch format {
+	case "jpeg", "jpg":
+		return injectJPEG(data, m)
+	case "png":
+		return injectPNG(data, m)
+	case "webp":
+		return injectWebP(data, m)
+	default:
+		return data, nil
+	}
+}
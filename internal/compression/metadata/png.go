@@ -0,0 +1,186 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// pngXMPKeyword identifies the iTXt chunk carrying XMP
+	pngXMPKeyword = []byte("XML:com.adobe.xmp")
+
+	// pngICCName is the profile name written into new iCCP chunks
+	pngICCName = []byte("ICC Profile")
+)
+
+// maxInflatedChunk caps how far a compressed iCCP or iTXt chunk may inflate.
+// Real profiles and XMP packets are far smaller; anything larger is dropped.
+const maxInflatedChunk = 4 << 20
+
+// pngChunk is a single chunk of a PNG stream
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// readPNGChunks splits a PNG stream into chunks, stopping at the first malformed one
+func readPNGChunks(data []byte) []pngChunk {
+	var chunks []pngChunk
+
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		if length < 0 || pos+12+length > len(data) {
+			break
+		}
+
+		chunks = append(chunks, pngChunk{
+			kind: string(data[pos+4 : pos+8]),
+			data: data[pos+8 : pos+8+length],
+		})
+		pos += 12 + length
+	}
+
+	return chunks
+}
+
+// extractPNG collects EXIF from eXIf, the profile from iCCP and XMP from iTXt
+func extractPNG(data []byte) *Metadata {
+	m := &Metadata{}
+
+	for _, chunk := range readPNGChunks(data) {
+		switch chunk.kind {
+		case "eXIf":
+			m.EXIF = chunk.data
+		case "iCCP":
+			m.ICC = readPNGICC(chunk.data)
+		case "iTXt":
+			if xmp := readPNGXMP(chunk.data); xmp != nil {
+				m.XMP = xmp
+			}
+		}
+	}
+
+	return m
+}
+
+// readPNGICC inflates an iCCP chunk: name, NUL, compression method, zlib data
+func readPNGICC(data []byte) []byte {
+	nameEnd := bytes.IndexByte(data, 0)
+	if nameEnd < 0 || nameEnd+2 > len(data) || data[nameEnd+1] != 0 {
+		return nil
+	}
+
+	return inflateChunk(data[nameEnd+2:])
+}
+
+// readPNGXMP returns the text of an XMP iTXt chunk, or nil for other iTXt chunks.
+// Layout: keyword, NUL, compression flag, method, language, NUL, translated keyword, NUL, text.
+func readPNGXMP(data []byte) []byte {
+	keywordEnd := len(pngXMPKeyword)
+	if !bytes.HasPrefix(data, pngXMPKeyword) || len(data) <= keywordEnd || data[keywordEnd] != 0 {
+		return nil
+	}
+
+	rest := data[len(pngXMPKeyword)+1:]
+	if len(rest) < 2 {
+		return nil
+	}
+	compressed := rest[0] == 1
+	rest = rest[2:]
+
+	// Skip the language tag and translated keyword
+	for i := 0; i < 2; i++ {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return nil
+		}
+		rest = rest[end+1:]
+	}
+
+	if !compressed {
+		return rest
+	}
+
+	return inflateChunk(rest)
+}
+
+// inflateChunk decompresses zlib chunk data, or returns nil when it is
+// malformed or inflates past maxInflatedChunk
+func inflateChunk(data []byte) []byte {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	// Read one byte past the cap to tell a full chunk from a truncated one
+	inflated, err := io.ReadAll(io.LimitReader(reader, maxInflatedChunk+1))
+	if err != nil || len(inflated) > maxInflatedChunk {
+		return nil
+	}
+	return inflated
+}
+
+// injectPNG inserts iCCP, eXIf and iTXt chunks right after IHDR
+func injectPNG(data []byte, m *Metadata) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data, nil
+	}
+
+	// IHDR is always the first chunk and has a fixed 13-byte body
+	ihdrEnd := len(pngSignature) + 12 + 13
+	if len(data) < ihdrEnd {
+		return data, nil
+	}
+
+	var chunks bytes.Buffer
+	if len(m.ICC) > 0 {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(m.ICC); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		body := append(append(append([]byte(nil), pngICCName...), 0, 0), compressed.Bytes()...)
+		writePNGChunk(&chunks, "iCCP", body)
+	}
+	if len(m.EXIF) > 0 {
+		writePNGChunk(&chunks, "eXIf", m.EXIF)
+	}
+	if len(m.XMP) > 0 {
+		// Uncompressed, no language tag, no translated keyword
+		body := append(append([]byte(nil), pngXMPKeyword...), 0, 0, 0, 0, 0)
+		writePNGChunk(&chunks, "iTXt", append(body, m.XMP...))
+	}
+
+	out := make([]byte, 0, len(data)+chunks.Len())
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks.Bytes()...)
+	out = append(out, data[ihdrEnd:]...)
+	return out, nil
+}
+
+// writePNGChunk writes a length-prefixed, CRC-terminated chunk
+func writePNGChunk(buf *bytes.Buffer, kind string, body []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(body)))
+	copy(header[4:], kind)
+	buf.Write(header[:])
+	buf.Write(body)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(body)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
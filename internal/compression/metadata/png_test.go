@@ -0,0 +1,32 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+)
+
+// iccpChunk builds an iCCP chunk body holding the compressed profile
+func iccpChunk(profile []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ICC Profile\x00\x00")
+	writer := zlib.NewWriter(&buf)
+	writer.Write(profile)
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestReadPNGICC(t *testing.T) {
+	profile := bytes.Repeat([]byte("icc"), 1000)
+	if got := readPNGICC(iccpChunk(profile)); !bytes.Equal(got, profile) {
+		t.Errorf("readPNGICC returned %d bytes, want %d", len(got), len(profile))
+	}
+}
+
+func TestReadPNGICCDropsOversizedChunks(t *testing.T) {
+	// A few KB of zlib that inflates past the cap
+	bomb := iccpChunk(make([]byte, maxInflatedChunk+1))
+	if got := readPNGICC(bomb); got != nil {
+		t.Errorf("readPNGICC returned %d bytes, want nil", len(got))
+	}
+}
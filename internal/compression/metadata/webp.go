@@ -0,0 +1,158 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/riff"
+)
+
+// webpChunk is a single RIFF chunk of a WebP file
+type webpChunk struct {
+	fourCC  string
+	payload []byte
+}
+
+// readWebPChunks splits a WebP file into its RIFF chunks
+func readWebPChunks(data []byte) []webpChunk {
+	var chunks []webpChunk
+
+	pos := 12
+	for pos+8 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		if size < 0 || pos+8+size > len(data) {
+			break
+		}
+
+		chunks = append(chunks, webpChunk{
+			fourCC:  string(data[pos : pos+4]),
+			payload: data[pos+8 : pos+8+size],
+		})
+
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+	}
+
+	return chunks
+}
+
+// extractWebP collects the EXIF, ICCP and XMP chunks
+func extractWebP(data []byte) *Metadata {
+	m := &Metadata{}
+
+	for _, chunk := range readWebPChunks(data) {
+		switch chunk.fourCC {
+		case "EXIF":
+			m.EXIF = bytes.TrimPrefix(chunk.payload, jpegExifID)
+		case "ICCP":
+			m.ICC = chunk.payload
+		case "XMP ":
+			m.XMP = chunk.payload
+		}
+	}
+
+	return m
+}
+
+// injectWebP rewrites the file in the extended (VP8X) layout with the
+// metadata chunks placed where the container spec expects them
+func injectWebP(data []byte, m *Metadata) ([]byte, error) {
+	if !riff.IsWebP(data) {
+		return data, nil
+	}
+
+	chunks := readWebPChunks(data)
+	if len(chunks) == 0 {
+		return data, nil
+	}
+
+	// Start from the existing VP8X header, or derive one from the bitstream
+	var vp8x []byte
+	var body []webpChunk
+	if chunks[0].fourCC == "VP8X" && len(chunks[0].payload) >= 10 {
+		vp8x = append([]byte(nil), chunks[0].payload[:10]...)
+		chunks = chunks[1:]
+	} else {
+		width, height, alpha, ok := webpCanvas(chunks)
+		if !ok {
+			return data, nil
+		}
+
+		vp8x = make([]byte, 10)
+		if alpha {
+			vp8x[0] |= riff.VP8XFlagAlpha
+		}
+		riff.PutUint24(vp8x[4:7], uint32(width-1))
+		riff.PutUint24(vp8x[7:10], uint32(height-1))
+	}
+
+	// Drop metadata that will be replaced
+	for _, chunk := range chunks {
+		switch chunk.fourCC {
+		case "ICCP", "EXIF", "XMP ":
+			continue
+		}
+		body = append(body, chunk)
+	}
+
+	vp8x[0] &^= riff.VP8XFlagICC | riff.VP8XFlagEXIF | riff.VP8XFlagXMP
+	if len(m.ICC) > 0 {
+		vp8x[0] |= riff.VP8XFlagICC
+	}
+	if len(m.EXIF) > 0 {
+		vp8x[0] |= riff.VP8XFlagEXIF
+	}
+	if len(m.XMP) > 0 {
+		vp8x[0] |= riff.VP8XFlagXMP
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	riff.WriteChunk(&out, "VP8X", vp8x)
+	if len(m.ICC) > 0 {
+		riff.WriteChunk(&out, "ICCP", m.ICC)
+	}
+	for _, chunk := range body {
+		riff.WriteChunk(&out, chunk.fourCC, chunk.payload)
+	}
+	if len(m.EXIF) > 0 {
+		riff.WriteChunk(&out, "EXIF", m.EXIF)
+	}
+	if len(m.XMP) > 0 {
+		riff.WriteChunk(&out, "XMP ", m.XMP)
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
+
+// webpCanvas reads the dimensions and alpha usage of a simple-format WebP
+func webpCanvas(chunks []webpChunk) (width, height int, alpha bool, ok bool) {
+	for _, chunk := range chunks {
+		payload := chunk.payload
+		switch chunk.fourCC {
+		case "ALPH":
+			alpha = true
+		case "VP8 ":
+			// Frame tag (3 bytes), start code (3 bytes), then 14-bit dimensions
+			if len(payload) < 10 {
+				return 0, 0, false, false
+			}
+			width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3FFF)
+			height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3FFF)
+			return width, height, alpha, true
+		case "VP8L":
+			// Signature byte, then width-1 and height-1 in 14 bits each and the alpha hint
+			if len(payload) < 5 || payload[0] != 0x2F {
+				return 0, 0, false, false
+			}
+			bits := binary.LittleEndian.Uint32(payload[1:5])
+			width = int(bits&0x3FFF) + 1
+			height = int((bits>>14)&0x3FFF) + 1
+			alpha = alpha || (bits>>28)&1 == 1
+			return width, height, alpha, true
+		}
+	}
+	return 0, 0, false, false
+}
@@ -0,0 +1,112 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+)
+
+// DefaultTargetSSIM is used when a perceptual search is requested without a target
+const DefaultTargetSSIM = 0.95
+
+// PerceptualAlgorithm applies the requested resize and searches for the lowest
+// encoder quality whose output still reaches the target SSIM
+type PerceptualAlgorithm struct{}
+
+func NewPerceptualAlgorithm() *PerceptualAlgorithm {
+	return &PerceptualAlgorithm{}
+}
+
+func (a *PerceptualAlgorithm) Name() string {
+	return "perceptual"
+}
+
+func (a *PerceptualAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	return NewScaleAlgorithm().Process(ctx, img, params)
+}
+
+// Encode binary-searches quality against the SSIM of the decoded output.
+// If even the highest quality misses the target, that encode is returned with
+// its achieved score.
+func (a *PerceptualAlgorithm) Encode(ctx context.Context, p *ImageProcessor, img image.Image, format string, params CompressionParams) (*EncodeResult, error) {
+	target := params.TargetSSIM
+	if target <= 0 {
+		target = DefaultTargetSSIM
+	}
+
+	maxQuality := params.Quality
+	if maxQuality <= 0 || maxQuality > 100 {
+		maxQuality = 100
+	}
+
+	passes := 0
+	encode := func(q int) (*EncodeResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		passes++
+		attempt := params
+		attempt.Quality = q
+		data, err := p.EncodeToFormat(img, format, attempt)
+		if err != nil {
+			return nil, err
+		}
+		return scoreEncoding(img, data, q)
+	}
+
+	best, err := encode(maxQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lossless output or a target that cannot be reached needs no search
+	if hasQualitySetting(format, params) && best.SSIM >= target {
+		lo, hi := minSearchQuality, maxQuality-1
+		for lo <= hi {
+			mid := (lo + hi) / 2
+			candidate, err := encode(mid)
+			if err != nil {
+				return nil, err
+			}
+
+			if candidate.SSIM >= target {
+				best = candidate
+				hi = mid - 1
+			} else {
+				lo = mid + 1
+			}
+		}
+	}
+
+	// PSNR is only reported, so it is measured once for the chosen encode
+	decoded, _, err := image.Decode(bytes.NewReader(best.Data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding output for scoring: %v", err)
+	}
+	if best.PSNR, err = PSNR(img, decoded); err != nil {
+		return nil, err
+	}
+
+	best.Passes = passes
+	return best, nil
+}
+
+// scoreEncoding decodes encoded output and measures its SSIM against the source image
+func scoreEncoding(source image.Image, data []byte, quality int) (*EncodeResult, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding output for scoring: %v", err)
+	}
+
+	ssim, err := SSIM(source, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncodeResult{
+		Data:    data,
+		Quality: quality,
+		SSIM:    ssim,
+	}, nil
+}
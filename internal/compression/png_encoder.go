@@ -0,0 +1,163 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// maxPaletteColors is the most colors a PNG palette can hold
+const maxPaletteColors = 256
+
+// ParsePNGCompression parses a zlib effort name, empty selects the default
+func ParsePNGCompression(s string) (png.CompressionLevel, error) {
+	switch strings.ToLower(s) {
+	case "", "default":
+		return png.DefaultCompression, nil
+	case "none", "off":
+		return png.NoCompression, nil
+	case "fast", "speed":
+		return png.BestSpeed, nil
+	case "best", "size":
+		return png.BestCompression, nil
+	default:
+		return png.DefaultCompression, fmt.Errorf("unknown png compression level: %s", s)
+	}
+}
+
+// encodePNG writes img as PNG at the smallest lossless pixel format, or as a
+// quantized palette image when params.Colors asks for one
+func encodePNG(w io.Writer, img image.Image, params CompressionParams) error {
+	if params.Colors > 0 {
+		img = quantize(img, params.Colors, !params.DisableDither)
+	}
+
+	encoder := png.Encoder{CompressionLevel: params.PNGCompression}
+	reduced := reducePNG(img)
+
+	// The encoder never filters palette rows, so a lossless palette can
+	// deflate worse than filtered truecolor; keep whichever is smaller
+	if _, ok := reduced.(*image.Paletted); ok && reduced != img {
+		var palette, truecolor bytes.Buffer
+		if err := encoder.Encode(&palette, reduced); err != nil {
+			return err
+		}
+		if err := encoder.Encode(&truecolor, toNRGBA(img)); err != nil {
+			return err
+		}
+		smaller := &palette
+		if truecolor.Len() < palette.Len() {
+			smaller = &truecolor
+		}
+		_, err := smaller.WriteTo(w)
+		return err
+	}
+
+	return encoder.Encode(w, reduced)
+}
+
+// reducePNG picks the cheapest pixel format that holds img without loss:
+// 8-bit grayscale, a palette, or 8 bits per channel instead of 16
+func reducePNG(img image.Image) image.Image {
+	switch img.(type) {
+	case *image.Paletted, *image.Gray:
+		return img
+	case *image.Gray16, *image.RGBA64, *image.NRGBA64:
+		if !fitsIn8Bits(img) {
+			return img
+		}
+	}
+
+	src := toNRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	opaque, gray := true, true
+	colors := make(map[color.NRGBA]uint8)
+	palette := make(color.Palette, 0, maxPaletteColors)
+	for y := 0; y < height; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			c := color.NRGBA{row[x], row[x+1], row[x+2], row[x+3]}
+			if c.A != 0xFF {
+				opaque = false
+			}
+			if c.R != c.G || c.G != c.B {
+				gray = false
+			}
+			if palette == nil {
+				continue
+			}
+			if _, ok := colors[c]; !ok {
+				if len(palette) == maxPaletteColors {
+					palette = nil
+					continue
+				}
+				colors[c] = uint8(len(palette))
+				palette = append(palette, c)
+			}
+		}
+	}
+
+	if opaque && gray {
+		out := image.NewGray(src.Rect)
+		for i := 0; i < len(out.Pix); i++ {
+			y, x := i/width, i%width
+			out.Pix[i] = src.Pix[y*src.Stride+x*4]
+		}
+		return out
+	}
+
+	if palette != nil {
+		out := image.NewPaletted(src.Rect, palette)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				i := y*src.Stride + x*4
+				out.Pix[y*out.Stride+x] = colors[color.NRGBA{src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3]}]
+			}
+		}
+		return out
+	}
+
+	return src
+}
+
+// fitsIn8Bits reports whether every 16-bit sample of img has equal high and
+// low bytes, i.e. it came from an 8-bit value and can be stored as one
+func fitsIn8Bits(img image.Image) bool {
+	var pix []uint8
+	switch m := img.(type) {
+	case *image.Gray16:
+		pix = m.Pix
+	case *image.RGBA64:
+		pix = m.Pix
+	case *image.NRGBA64:
+		pix = m.Pix
+	default:
+		return true
+	}
+
+	for i := 0; i+1 < len(pix); i += 2 {
+		if pix[i] != pix[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// toNRGBA returns img as non-premultiplied 8-bit pixels anchored at the origin
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	if nrgba, ok := img.(*image.NRGBA); ok && bounds.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	return nrgba
+}
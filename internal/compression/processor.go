@@ -2,41 +2,70 @@ package compression
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
 	"io"
+	"sort"
 
-	"github.com/chai2010/webp"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/metadata"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
 )
 
+// optionalAlgorithms are added by files behind build tags, like vips
+var optionalAlgorithms []func() Algorithm
+
+// ErrUnknownAlgorithm is returned for an algorithm name that is not registered
+var ErrUnknownAlgorithm = errors.New("unknown compression algorithm")
+
 // ImageProcessor handles the common image processing operations
 type ImageProcessor struct {
-	algorithms     map[string]CompressionAlgorithm
-	defaultAlgorithm CompressionAlgorithm
+	algorithms     map[string]Algorithm
+	defaultAlgorithm Algorithm
+	encoders       map[string]Encoder
+	pool           *worker.Pool
+	maxPixels      int64
 }
 
 // NewImageProcessor creates a new ImageProcessor
 func NewImageProcessor() *ImageProcessor {
 	processor := &ImageProcessor{
-		algorithms: make(map[string]CompressionAlgorithm),
+		algorithms: make(map[string]Algorithm),
+		encoders:   make(map[string]Encoder),
 	}
 
+	// Register the built-in output formats
+	processor.registerBuiltinEncoders()
+
 	// Register default (scale) algorithm
 	scaleAlgorithm := NewScaleAlgorithm()
 	processor.RegisterAlgorithm(scaleAlgorithm)
 	processor.defaultAlgorithm = scaleAlgorithm
 
-	// // Register libvips algorithm
-	// vipsAlgorithm := NewVipsAlgorithm()
-	// processor.RegisterAlgorithm(vipsAlgorithm)
+	// Register legacy (quality-coupled scale) algorithm
+	processor.RegisterAlgorithm(NewLegacyScaleAlgorithm())
+
+	// Register perceptual (SSIM target) algorithm
+	processor.RegisterAlgorithm(NewPerceptualAlgorithm())
+
+	// Register quality-only algorithm that never resizes
+	processor.RegisterAlgorithm(NewQualityModAlgorithm())
+
+	// Register algorithms compiled in with build tags, such as libvips.
+	// A backend whose library fails to start returns nil and is left out.
+	for _, newAlgorithm := range optionalAlgorithms {
+		if algorithm := newAlgorithm(); algorithm != nil {
+			processor.RegisterAlgorithm(algorithm)
+		}
+	}
 
 	return processor
 }
 
-// RegisterAlgorithm registers a compression algorithm
-func (p *ImageProcessor) RegisterAlgorithm(algorithm CompressionAlgorithm) {
+// RegisterAlgorithm registers a compression algorithm. Algorithms written
+// against the older CompressionAlgorithm interface go through AdaptAlgorithm.
+func (p *ImageProcessor) RegisterAlgorithm(algorithm Algorithm) {
 	p.algorithms[algorithm.Name()] = algorithm
 }
 
@@ -50,51 +79,238 @@ func (p *ImageProcessor) SetDefaultAlgorithm(name string) bool {
 }
 
 // GetAlgorithm returns the algorithm with the given name
-func (p *ImageProcessor) GetAlgorithm(name string) (CompressionAlgorithm, bool) {
+func (p *ImageProcessor) GetAlgorithm(name string) (Algorithm, bool) {
 	algorithm, exists := p.algorithms[name]
 	return algorithm, exists
 }
 
 // GetDefaultAlgorithm returns the default algorithm
-func (p *ImageProcessor) GetDefaultAlgorithm() CompressionAlgorithm {
+func (p *ImageProcessor) GetDefaultAlgorithm() Algorithm {
 	return p.defaultAlgorithm
 }
 
+// ResolveAlgorithm returns the named algorithm, or the default for an empty name
+func (p *ImageProcessor) ResolveAlgorithm(name string) (Algorithm, error) {
+	if name == "" {
+		return p.defaultAlgorithm, nil
+	}
+
+	algorithm, exists := p.algorithms[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+	}
+	return algorithm, nil
+}
+
+// Algorithms returns the names of the registered algorithms, sorted
+func (p *ImageProcessor) Algorithms() []string {
+	names := make([]string, 0, len(p.algorithms))
+	for name := range p.algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // ProcessImage handles the complete process: decoding, compressing, and encoding
-func (p *ImageProcessor) ProcessImage(input io.Reader, format string, quality int, algorithm CompressionAlgorithm) ([]byte, error) {
-	// Decode the image
-	img, _, err := image.Decode(input)
+func (p *ImageProcessor) ProcessImage(ctx context.Context, input io.Reader, format string, params CompressionParams, algorithm Algorithm) (*EncodeResult, error) {
+	// Keep the raw bytes around, the metadata lives outside the pixel data
+	inputData, err := io.ReadAll(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading image: %v", err)
+	}
+
+	// Resizes are held to the same pixel limit as the source
+	if params.MaxPixels <= 0 {
+		params.MaxPixels = p.maxPixels
+	}
+
+	// Refuse oversized images before decoding any pixels
+	if _, err := p.EstimateMemory(inputData, format, params); err != nil {
+		return nil, err
+	}
+
+	// Animated GIFs keep all of their frames
+	if isGIF(inputData) {
+		animation, err := decodeGIFAnimation(inputData, p.maxPixels)
+		if err != nil {
+			return nil, err
+		}
+		if len(animation.Frames) > 1 {
+			return p.processAnimation(ctx, animation, format, params, algorithm)
+		}
+	}
+
+	// Don't start decoding for a caller that has already gone
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sourceMetadata := metadata.Extract(inputData)
+	keptMetadata := sourceMetadata.Filter(params.Metadata)
+
+	// Algorithms that read the encoded input skip the Go decode entirely.
+	// Byte budgets, format selection and wide-gamut sources need the pixels.
+	if source, ok := algorithm.(SourceAlgorithm); ok && params.MaxBytes <= 0 &&
+		format != FormatAuto && format != FormatBest && !needsSRGBConversion(sourceMetadata.ICC) {
+		result, handled, err := source.EncodeSource(ctx, inputData, format, params)
+		if err != nil {
+			return nil, fmt.Errorf("%s algorithm: %w", algorithm.Name(), err)
+		}
+		if handled {
+			// The algorithm applies the EXIF orientation like the pixel pipeline
+			if !params.DisableAutoOrient && sourceMetadata.Orientation() != 1 {
+				keptMetadata.ResetOrientation()
+			}
+			return finishResult(result, format, keptMetadata, false)
+		}
+	}
+
+	// Decode the image, or the requested page of a multi-page TIFF
+	var img image.Image
+	if isTIFF(inputData) {
+		img, err = decodeTIFFPage(inputData, params.Page)
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(inputData))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error decoding image: %v", err)
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Move wide-gamut pixels to sRGB, the original profile no longer describes them
+	img, colorConverted := convertToSRGB(img, sourceMetadata.ICC)
+	if colorConverted {
+		keptMetadata.ICC = nil
+	}
+
+	// Turn phone photos upright before any resizing
+	if !params.DisableAutoOrient {
+		if orientation := sourceMetadata.Orientation(); orientation != 1 {
+			img = applyOrientation(img, orientation)
+			keptMetadata.ResetOrientation()
+		}
+	}
+
+	// Kept metadata counts against the byte budget
+	maxBytes := params.MaxBytes
+	if maxBytes > 0 && !keptMetadata.Empty() {
+		params.MaxBytes -= keptMetadata.Size()
+		if params.MaxBytes <= 0 {
+			return nil, fmt.Errorf("%w: metadata alone needs %d bytes", ErrBudgetUnreachable, keptMetadata.Size())
+		}
+	}
 
 	// Compress the image using the algorithm
-	params := CompressionParams{
-		Quality: quality,
+	compressedImg, err := algorithm.Process(ctx, img, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s algorithm: %w", algorithm.Name(), err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := p.encodeFinal(ctx, compressedImg, format, params, algorithm, keptMetadata, colorConverted)
+		if err != nil || maxBytes <= 0 || len(result.Data) <= maxBytes {
+			return result, err
+		}
+
+		// The metadata's segment headers, chunk framing and VP8X header took the
+		// output over the budget, so take the excess off the image and try again
+		params.MaxBytes -= len(result.Data) - maxBytes
+		if attempt == maxMetadataRetries || params.MaxBytes <= 0 {
+			return nil, fmt.Errorf("%w: %d bytes with metadata", ErrBudgetUnreachable, len(result.Data))
+		}
+	}
+}
+
+// maxMetadataRetries bounds the re-encodes when injected metadata overshoots
+// the byte budget; the container overhead barely changes between attempts
+const maxMetadataRetries = 2
+
+// encodeFinal encodes the processed image to format, or the smallest candidate
+// for FormatBest, and injects the kept metadata
+func (p *ImageProcessor) encodeFinal(ctx context.Context, img image.Image, format string, params CompressionParams, algorithm Algorithm, keptMetadata *metadata.Metadata, colorConverted bool) (*EncodeResult, error) {
+	var result *EncodeResult
+	var err error
+	if format == FormatBest {
+		result, err = p.encodeBest(ctx, img, params, algorithm)
+	} else {
+		// Pick a concrete format now that the final pixels are known
+		format = resolveFormat(format, img)
+		result, err = p.encodeImage(ctx, img, format, params, algorithm)
 	}
-	compressedImg := algorithm.CompressImage(img, params)
-	
+	if err != nil {
+		return nil, err
+	}
+
+	return finishResult(result, format, keptMetadata, colorConverted)
+}
+
+// finishResult fills in the output format, unless the encoder already chose
+// one, and copies the metadata the policy allows into the output
+func finishResult(result *EncodeResult, format string, keptMetadata *metadata.Metadata, colorConverted bool) (*EncodeResult, error) {
+	if result.Format == "" {
+		result.Format = format
+	}
+
+	var err error
+	result.Data, err = metadata.Inject(result.Data, result.Format, keptMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("error writing metadata: %v", err)
+	}
+	result.ColorConverted = colorConverted
+
+	return result, nil
+}
+
+// encodeImage encodes the compressed image, searching settings when the
+// algorithm or a byte budget asks for it
+func (p *ImageProcessor) encodeImage(ctx context.Context, img image.Image, format string, params CompressionParams, algorithm Algorithm) (*EncodeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Let algorithms that write their own output do so
+	if encoder, ok := algorithm.(EncodingAlgorithm); ok {
+		result, err := encoder.Encode(ctx, p, img, format, params)
+		if err != nil {
+			return nil, err
+		}
+		if params.MaxBytes > 0 && len(result.Data) > params.MaxBytes {
+			return nil, fmt.Errorf("%w: %d bytes needed for SSIM %.3f", ErrBudgetUnreachable, len(result.Data), result.SSIM)
+		}
+		return result, nil
+	}
+
+	// Search for settings that fit the byte budget, if one was given
+	if params.MaxBytes > 0 {
+		return p.EncodeWithinBudget(ctx, img, format, params)
+	}
+
 	// Encode the image to the requested format
-	return p.EncodeToFormat(compressedImg, format, quality)
+	data, err := p.EncodeToFormat(img, format, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncodeResult{
+		Data:    data,
+		Quality: params.Quality,
+		Passes:  1,
+	}, nil
 }
 
-// EncodeToFormat encodes an image to the specified format with the given quality
-func (p *ImageProcessor) EncodeToFormat(img image.Image, format string, quality int) ([]byte, error) {
+// EncodeToFormat encodes an image to the specified format with the encoder settings in params
+func (p *ImageProcessor) EncodeToFormat(img image.Image, format string, params CompressionParams) ([]byte, error) {
 	var buf bytes.Buffer
-	var err error
 
-	switch format {
-	case "webp":
-		err = webp.Encode(&buf, img, &webp.Options{Quality: float32(quality)})
-	case "jpeg", "jpg":
-		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
-	case "png":
-		err = png.Encode(&buf, img)
-	default:
+	encoder, ok := p.GetEncoder(format)
+	if !ok {
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
 
-	if err != nil {
+	if err := encoder.Encode(&buf, img, params); err != nil {
 		return nil, fmt.Errorf("error encoding image to %s: %v", format, err)
 	}
 
@@ -0,0 +1,22 @@
+package compression
+
+import (
+	"context"
+	"image"
+)
+
+// QualityModAlgorithm keeps the source dimensions and only varies the encoder
+// quality; requested sizes and scales are ignored
+type QualityModAlgorithm struct{}
+
+func NewQualityModAlgorithm() *QualityModAlgorithm {
+	return &QualityModAlgorithm{}
+}
+
+func (a *QualityModAlgorithm) Name() string {
+	return "qualitymod"
+}
+
+func (a *QualityModAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	return img, ctx.Err()
+}
@@ -0,0 +1,157 @@
+package compression
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// colorCount is a distinct color and how many pixels use it
+type colorCount struct {
+	color  [4]uint8
+	pixels int
+}
+
+// colorBox is a group of colors that will share one palette entry
+type colorBox struct {
+	colors []colorCount
+	pixels int
+}
+
+// quantize reduces img to at most n colors with median cut, optionally
+// spreading the error with Floyd-Steinberg dithering
+func quantize(img image.Image, n int, dither bool) *image.Paletted {
+	if n > maxPaletteColors {
+		n = maxPaletteColors
+	}
+	if n < 2 {
+		n = 2
+	}
+
+	src := toNRGBA(img)
+	palette := medianCut(histogram(src), n)
+
+	dst := image.NewPaletted(src.Rect, palette)
+	if dither {
+		draw.FloydSteinberg.Draw(dst, dst.Rect, src, image.Point{})
+	} else {
+		draw.Draw(dst, dst.Rect, src, image.Point{}, draw.Src)
+	}
+	return dst
+}
+
+// histogram counts the distinct colors of img
+func histogram(img *image.NRGBA) []colorCount {
+	counts := make(map[[4]uint8]int)
+	width := img.Rect.Dx()
+	for y := 0; y < img.Rect.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			counts[[4]uint8{row[x], row[x+1], row[x+2], row[x+3]}]++
+		}
+	}
+
+	colors := make([]colorCount, 0, len(counts))
+	for c, pixels := range counts {
+		colors = append(colors, colorCount{color: c, pixels: pixels})
+	}
+	return colors
+}
+
+// medianCut splits the color space into n boxes, each time cutting the box
+// with the widest spread at the pixel median of that channel, and returns
+// the pixel-weighted average of every box
+func medianCut(colors []colorCount, n int) color.Palette {
+	boxes := []colorBox{newColorBox(colors)}
+
+	for len(boxes) < n {
+		index, channel, best := -1, 0, 0
+		for i, box := range boxes {
+			c, spread := box.widestChannel()
+			if score := spread * box.pixels; spread > 0 && score > best {
+				index, channel, best = i, c, score
+			}
+		}
+		if index < 0 {
+			// Every box is a single color already
+			break
+		}
+
+		low, high := boxes[index].split(channel)
+		boxes[index] = low
+		boxes = append(boxes, high)
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		palette[i] = box.average()
+	}
+	return palette
+}
+
+func newColorBox(colors []colorCount) colorBox {
+	box := colorBox{colors: colors}
+	for _, c := range colors {
+		box.pixels += c.pixels
+	}
+	return box
+}
+
+// widestChannel returns the channel with the largest value range and that range
+func (b colorBox) widestChannel() (int, int) {
+	channel, spread := 0, 0
+	for c := 0; c < 4; c++ {
+		lo, hi := 255, 0
+		for _, entry := range b.colors {
+			v := int(entry.color[c])
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > spread {
+			channel, spread = c, hi-lo
+		}
+	}
+	return channel, spread
+}
+
+// split cuts the box in two at the pixel median along channel
+func (b colorBox) split(channel int) (colorBox, colorBox) {
+	sort.Slice(b.colors, func(i, j int) bool {
+		return b.colors[i].color[channel] < b.colors[j].color[channel]
+	})
+
+	cut, seen := 1, 0
+	for i, entry := range b.colors[:len(b.colors)-1] {
+		seen += entry.pixels
+		cut = i + 1
+		if seen*2 >= b.pixels {
+			break
+		}
+	}
+
+	return newColorBox(b.colors[:cut]), newColorBox(b.colors[cut:])
+}
+
+// average is the pixel-weighted mean color of the box
+func (b colorBox) average() color.NRGBA {
+	var sum [4]int
+	for _, entry := range b.colors {
+		for c := 0; c < 4; c++ {
+			sum[c] += int(entry.color[c]) * entry.pixels
+		}
+	}
+
+	half := b.pixels / 2
+	return color.NRGBA{
+		R: uint8((sum[0] + half) / b.pixels),
+		G: uint8((sum[1] + half) / b.pixels),
+		B: uint8((sum[2] + half) / b.pixels),
+		A: uint8((sum[3] + half) / b.pixels),
+	}
+}
@@ -0,0 +1,317 @@
+package compression
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+	"golang.org/x/image/draw"
+)
+
+// FitMode controls how an image is fitted into a width x height box
+type FitMode string
+
+const (
+	// FitFill stretches the image to exactly the box, ignoring the aspect ratio
+	FitFill FitMode = "fill"
+
+	// FitContain fits the whole image in the box and letterboxes the rest with the background
+	FitContain FitMode = "contain"
+
+	// FitCover fills the box and crops the overflow according to the gravity
+	FitCover FitMode = "cover"
+
+	// FitInside shrinks the image to fit the box without padding, so the output may be smaller
+	FitInside FitMode = "inside"
+
+	// FitOutside scales the image to cover the box without cropping, so the output may be larger
+	FitOutside FitMode = "outside"
+)
+
+// Gravity anchors the image when cropping (cover) or letterboxing (contain)
+type Gravity string
+
+const (
+	GravityCenter    Gravity = "center"
+	GravityNorth     Gravity = "north"
+	GravityNorthEast Gravity = "northeast"
+	GravityEast      Gravity = "east"
+	GravitySouthEast Gravity = "southeast"
+	GravitySouth     Gravity = "south"
+	GravitySouthWest Gravity = "southwest"
+	GravityWest      Gravity = "west"
+	GravityNorthWest Gravity = "northwest"
+)
+
+// MaxDimension caps the output width and height of any resize
+const MaxDimension = 16384
+
+// DefaultBackground is used to letterbox contained images when none is given
+var DefaultBackground = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+
+// ParseFitMode validates a fit mode name, the empty string selects cover so a
+// width x height box never distorts the image unless fill is asked for
+func ParseFitMode(value string) (FitMode, error) {
+	switch fit := FitMode(strings.ToLower(value)); fit {
+	case "":
+		return FitCover, nil
+	case FitFill, FitContain, FitCover, FitInside, FitOutside:
+		return fit, nil
+	default:
+		return "", fmt.Errorf("unknown fit mode: %s", value)
+	}
+}
+
+// ParseGravity validates a gravity name. Compass names may be written with
+// dashes or underscores (south-east) and the empty string selects center.
+func ParseGravity(value string) (Gravity, error) {
+	normalized := strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(value))
+
+	switch gravity := Gravity(normalized); gravity {
+	case "", "centre":
+		return GravityCenter, nil
+	case GravityCenter, GravityNorth, GravityNorthEast, GravityEast, GravitySouthEast,
+		GravitySouth, GravitySouthWest, GravityWest, GravityNorthWest:
+		return gravity, nil
+	default:
+		return "", fmt.Errorf("unknown gravity: %s", value)
+	}
+}
+
+// ParseColor parses a #rgb, #rrggbb or #rrggbbaa hex color
+func ParseColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", value)
+	}
+
+	rgba, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", value)
+	}
+
+	return color.NRGBA{
+		R: uint8(rgba >> 24),
+		G: uint8(rgba >> 16),
+		B: uint8(rgba >> 8),
+		A: uint8(rgba),
+	}, nil
+}
+
+// Resize applies the resize requested in params. Fit modes only matter when
+// both width and height are given; otherwise the aspect ratio is always kept.
+// It fails with ErrImageTooLarge rather than allocate an output over
+// MaxDimension on a side or over params.MaxPixels in total.
+func Resize(img image.Image, params CompressionParams) (image.Image, error) {
+	bounds := img.Bounds()
+
+	if params.Width > 0 && params.Height > 0 {
+		return fitToBox(img, params)
+	}
+
+	newWidth, newHeight := targetDimensions(bounds.Dx(), bounds.Dy(), params)
+
+	// Nothing to do if no resize was requested
+	if newWidth == bounds.Dx() && newHeight == bounds.Dy() {
+		return img, nil
+	}
+
+	dst, err := newOutput(newWidth, newHeight, params)
+	if err != nil {
+		return nil, err
+	}
+	scaleInto(dst, dst.Bounds(), img, bounds, params.Kernel)
+	return dst, nil
+}
+
+// newOutput allocates a resize output, refusing sizes over the limits
+func newOutput(width, height int, params CompressionParams) (*image.RGBA, error) {
+	if err := checkOutputSize(width, height, params); err != nil {
+		return nil, err
+	}
+	return image.NewRGBA(image.Rect(0, 0, width, height)), nil
+}
+
+// checkOutputSize refuses resize outputs over MaxDimension on a side or over
+// params.MaxPixels in total
+func checkOutputSize(width, height int, params CompressionParams) error {
+	if width > MaxDimension || height > MaxDimension {
+		return fmt.Errorf("%w: output is over the %d pixel side limit", ErrImageTooLarge, MaxDimension)
+	}
+	if params.MaxPixels > 0 && int64(width)*int64(height) > params.MaxPixels {
+		return fmt.Errorf("%w: output %dx%d is over the %d pixel limit",
+			ErrImageTooLarge, width, height, params.MaxPixels)
+	}
+	return nil
+}
+
+// clampDimension converts a computed dimension to an int, capping it just
+// past MaxDimension so huge ratios can't overflow before they are refused
+func clampDimension(v float64) int {
+	return int(math.Max(math.Min(v, MaxDimension+1), 1))
+}
+
+// targetDimensions works out the output size from the explicit resize params.
+// Width and height take precedence over scale; a single dimension keeps the aspect ratio.
+func targetDimensions(width, height int, params CompressionParams) (int, int) {
+	newWidth, newHeight := width, height
+
+	switch {
+	case params.Width > 0 && params.Height > 0:
+		newWidth, newHeight = params.Width, params.Height
+	case params.Width > 0:
+		newWidth = params.Width
+		newHeight = clampDimension(math.Floor(float64(height) * float64(params.Width) / float64(width)))
+	case params.Height > 0:
+		newHeight = params.Height
+		newWidth = clampDimension(math.Floor(float64(width) * float64(params.Height) / float64(height)))
+	case params.Scale > 0 && params.Scale != 1:
+		newWidth = clampDimension(math.Floor(float64(width) * params.Scale))
+		newHeight = clampDimension(math.Floor(float64(height) * params.Scale))
+	}
+
+	// Never collapse an image to nothing
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	return newWidth, newHeight
+}
+
+// outputDimensions works out the size Resize will produce for a width x
+// height source without touching any pixels
+func outputDimensions(width, height int, params CompressionParams) (int, int) {
+	if params.Width <= 0 || params.Height <= 0 || width <= 0 || height <= 0 {
+		return targetDimensions(width, height, params)
+	}
+
+	scaleX := float64(params.Width) / float64(width)
+	scaleY := float64(params.Height) / float64(height)
+	switch params.Fit {
+	case FitInside:
+		return scaledSize(float64(width), float64(height), math.Min(scaleX, scaleY))
+	case FitOutside:
+		return scaledSize(float64(width), float64(height), math.Max(scaleX, scaleY))
+	default:
+		return params.Width, params.Height
+	}
+}
+
+// fitToBox fits the image into params.Width x params.Height using params.Fit
+func fitToBox(img image.Image, params CompressionParams) (image.Image, error) {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	boxWidth, boxHeight := params.Width, params.Height
+
+	scaleX := float64(boxWidth) / width
+	scaleY := float64(boxHeight) / height
+
+	switch params.Fit {
+	case FitContain:
+		ratio := math.Min(scaleX, scaleY)
+		scaledWidth, scaledHeight := scaledSize(width, height, ratio)
+
+		background := params.Background
+		if background == nil {
+			background = DefaultBackground
+		}
+
+		dst, err := newOutput(boxWidth, boxHeight, params)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+		offsetX, offsetY := gravityOffset(params.Gravity, boxWidth-scaledWidth, boxHeight-scaledHeight)
+		target := image.Rect(offsetX, offsetY, offsetX+scaledWidth, offsetY+scaledHeight)
+		scaleInto(dst, target, img, bounds, params.Kernel)
+		return dst, nil
+
+	case FitInside, FitOutside:
+		ratio := math.Min(scaleX, scaleY)
+		if params.Fit == FitOutside {
+			ratio = math.Max(scaleX, scaleY)
+		}
+		scaledWidth, scaledHeight := scaledSize(width, height, ratio)
+
+		dst, err := newOutput(scaledWidth, scaledHeight, params)
+		if err != nil {
+			return nil, err
+		}
+		scaleInto(dst, dst.Bounds(), img, bounds, params.Kernel)
+		return dst, nil
+
+	case FitFill:
+		dst, err := newOutput(boxWidth, boxHeight, params)
+		if err != nil {
+			return nil, err
+		}
+		scaleInto(dst, dst.Bounds(), img, bounds, params.Kernel)
+		return dst, nil
+
+	default:
+		// Cover, also when no fit mode is set. Crop the source to the box
+		// aspect ratio first, so only the kept area is resampled. A very thin
+		// box still keeps at least one source row or column
+		ratio := math.Max(scaleX, scaleY)
+		cropWidth := int(math.Max(1, math.Min(math.Round(float64(boxWidth)/ratio), width)))
+		cropHeight := int(math.Max(1, math.Min(math.Round(float64(boxHeight)/ratio), height)))
+
+		offsetX, offsetY := gravityOffset(params.Gravity, bounds.Dx()-cropWidth, bounds.Dy()-cropHeight)
+		crop := image.Rect(offsetX, offsetY, offsetX+cropWidth, offsetY+cropHeight).Add(bounds.Min)
+
+		dst, err := newOutput(boxWidth, boxHeight, params)
+		if err != nil {
+			return nil, err
+		}
+		scaleInto(dst, dst.Bounds(), img, crop, params.Kernel)
+		return dst, nil
+	}
+}
+
+// scaledSize multiplies both dimensions by ratio, keeping at least one pixel
+func scaledSize(width, height, ratio float64) (int, int) {
+	return clampDimension(math.Round(width * ratio)), clampDimension(math.Round(height * ratio))
+}
+
+// gravityOffset places content inside free horizontal and vertical space
+func gravityOffset(gravity Gravity, freeX, freeY int) (int, int) {
+	x, y := freeX/2, freeY/2
+
+	switch gravity {
+	case GravityNorthWest, GravityWest, GravitySouthWest:
+		x = 0
+	case GravityNorthEast, GravityEast, GravitySouthEast:
+		x = freeX
+	}
+
+	switch gravity {
+	case GravityNorthWest, GravityNorth, GravityNorthEast:
+		y = 0
+	case GravitySouthWest, GravitySouth, GravitySouthEast:
+		y = freeY
+	}
+
+	return x, y
+}
+
+// scaleInto resamples the sr part of src into the dr part of dst with the given kernel
+func scaleInto(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle, kernel Kernel) {
+	startTime := time.Now()
+	kernel.interpolator().Scale(dst, dr, src, sr, draw.Over, nil)
+	metrics.RecordResizeDuration(kernel.Name(), time.Since(startTime))
+}
@@ -0,0 +1,26 @@
+package compression
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestCoverKeepsAThinCrop(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 100, 1))
+	draw.Draw(src, src.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+	// The box's width scaled back to the source rounds to 0 columns
+	out, err := Resize(src, CompressionParams{Width: 1, Height: 100, Fit: FitCover})
+	if err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	if bounds := out.Bounds(); bounds.Dx() != 1 || bounds.Dy() != 100 {
+		t.Fatalf("output is %dx%d, want 1x100", bounds.Dx(), bounds.Dy())
+	}
+	if got := color.RGBAModel.Convert(out.At(0, 50)); got != red {
+		t.Errorf("output pixel is %v, want the source's %v", got, red)
+	}
+}
@@ -0,0 +1,41 @@
+// Package riff writes the RIFF chunks and VP8X headers of WebP files, shared
+// by the metadata injector and the animated WebP muxer
+package riff
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// VP8X feature flags
+const (
+	VP8XFlagICC       = 0x20
+	VP8XFlagAlpha     = 0x10
+	VP8XFlagEXIF      = 0x08
+	VP8XFlagXMP       = 0x04
+	VP8XFlagAnimation = 0x02
+)
+
+// IsWebP reports whether data starts with a RIFF WEBP header
+func IsWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// WriteChunk writes a RIFF chunk with its padding byte
+func WriteChunk(buf *bytes.Buffer, fourCC string, payload []byte) {
+	var header [8]byte
+	copy(header[:4], fourCC)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.Write(payload)
+	if len(payload)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// PutUint24 writes a little-endian 24-bit value
+func PutUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
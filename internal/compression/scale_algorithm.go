@@ -1,9 +1,8 @@
 package compression
 
 import (
+	"context"
 	"image"
-
-	"golang.org/x/image/draw"
 )
 
 type ScaleAlgorithm struct{}
@@ -16,30 +15,9 @@ func (a *ScaleAlgorithm) Name() string {
 	return "scale"
 }
 
-func (a *ScaleAlgorithm) CompressImage(img image.Image, params CompressionParams) image.Image {
-	// If quality is 100, return the original image
-	if params.Quality == 100 {
-		return img
-	}
-
-	bounds := img.Bounds()
-	width, height := bounds.Dx(), bounds.Dy()
-	
-	// Calculate new dimensions based on quality
-	scaleFactor := float64(params.Quality) / 100.0
-	newWidth := int(float64(width) * scaleFactor)
-	newHeight := int(float64(height) * scaleFactor)
-
-	// Ensure minimum dimensions
-	if newWidth < 10 {
-		newWidth = 10
+func (a *ScaleAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	if newHeight < 10 {
-		newHeight = 10
-	}
-
-	newImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	draw.ApproxBiLinear.Scale(newImg, newImg.Bounds(), img, img.Bounds(), draw.Over, nil)
-
-	return newImg
-}
\ No newline at end of file
+	return Resize(img, params)
+}
@@ -0,0 +1,160 @@
+package compression
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	// ssimWindow is the side of the square window SSIM is computed over
+	ssimWindow = 8
+
+	// ssimStride is the step between windows, half the window keeps overlap cheap
+	ssimStride = 4
+
+	// maxPSNR is reported for identical images instead of +Inf
+	maxPSNR = 100.0
+)
+
+// Stabilizing constants from the SSIM paper for 8-bit samples
+var (
+	ssimC1 = math.Pow(0.01*255, 2)
+	ssimC2 = math.Pow(0.03*255, 2)
+)
+
+// SSIM computes the mean structural similarity of the luma of two images.
+// The result is in [-1, 1], with 1 meaning identical.
+func SSIM(a, b image.Image) (float64, error) {
+	width, height, err := sameSize(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	lumaA := lumaPlane(a)
+	lumaB := lumaPlane(b)
+
+	// Images smaller than a window are compared as a single window
+	windowW, windowH := ssimWindow, ssimWindow
+	if width < windowW {
+		windowW = width
+	}
+	if height < windowH {
+		windowH = height
+	}
+
+	var total float64
+	var windows int
+	for y := 0; y+windowH <= height; y += ssimStride {
+		for x := 0; x+windowW <= width; x += ssimStride {
+			total += windowSSIM(lumaA, lumaB, width, x, y, windowW, windowH)
+			windows++
+		}
+	}
+
+	if windows == 0 {
+		return 1, nil
+	}
+	return total / float64(windows), nil
+}
+
+// windowSSIM computes SSIM for a single window of two luma planes
+func windowSSIM(a, b []float64, stride, x0, y0, w, h int) float64 {
+	n := float64(w * h)
+
+	var sumA, sumB float64
+	for y := y0; y < y0+h; y++ {
+		row := y * stride
+		for x := x0; x < x0+w; x++ {
+			sumA += a[row+x]
+			sumB += b[row+x]
+		}
+	}
+	meanA, meanB := sumA/n, sumB/n
+
+	var varA, varB, covar float64
+	for y := y0; y < y0+h; y++ {
+		row := y * stride
+		for x := x0; x < x0+w; x++ {
+			da := a[row+x] - meanA
+			db := b[row+x] - meanB
+			varA += da * da
+			varB += db * db
+			covar += da * db
+		}
+	}
+	varA /= n
+	varB /= n
+	covar /= n
+
+	numerator := (2*meanA*meanB + ssimC1) * (2*covar + ssimC2)
+	denominator := (meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2)
+	return numerator / denominator
+}
+
+// PSNR computes the peak signal-to-noise ratio in dB over the RGB channels.
+// Identical images report maxPSNR.
+func PSNR(a, b image.Image) (float64, error) {
+	width, height, err := sameSize(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	var sum float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			ca := color.NRGBAModel.Convert(a.At(boundsA.Min.X+x, boundsA.Min.Y+y)).(color.NRGBA)
+			cb := color.NRGBAModel.Convert(b.At(boundsB.Min.X+x, boundsB.Min.Y+y)).(color.NRGBA)
+			dr := float64(ca.R) - float64(cb.R)
+			dg := float64(ca.G) - float64(cb.G)
+			db := float64(ca.B) - float64(cb.B)
+			sum += dr*dr + dg*dg + db*db
+		}
+	}
+
+	mse := sum / float64(width*height*3)
+	if mse == 0 {
+		return maxPSNR, nil
+	}
+	return math.Min(10*math.Log10(255*255/mse), maxPSNR), nil
+}
+
+// sameSize returns the shared dimensions of two images or an error if they differ
+func sameSize(a, b image.Image) (int, int, error) {
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	if boundsA.Dx() != boundsB.Dx() || boundsA.Dy() != boundsB.Dy() {
+		return 0, 0, fmt.Errorf("image sizes differ: %dx%d vs %dx%d",
+			boundsA.Dx(), boundsA.Dy(), boundsB.Dx(), boundsB.Dy())
+	}
+	if boundsA.Empty() {
+		return 0, 0, fmt.Errorf("cannot compare empty images")
+	}
+	return boundsA.Dx(), boundsA.Dy(), nil
+}
+
+// lumaPlane extracts the BT.601 luma of an image as a row-major plane
+func lumaPlane(img image.Image) []float64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	plane := make([]float64, width*height)
+
+	// Decoded JPEG and WebP images already carry a luma plane
+	if ycbcr, ok := img.(*image.YCbCr); ok {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				plane[y*width+x] = float64(ycbcr.Y[ycbcr.YOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+			}
+		}
+		return plane
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			plane[y*width+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+		}
+	}
+	return plane
+}
@@ -0,0 +1,157 @@
+package compression
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+)
+
+const (
+	// minSearchQuality is the lowest encoder quality tried when fitting a byte budget
+	minSearchQuality = 5
+
+	// budgetScaleStep is the factor applied to the dimensions when even the
+	// lowest quality does not fit the budget
+	budgetScaleStep = 0.75
+
+	// minBudgetDimension stops the downscaling before the image becomes useless
+	minBudgetDimension = 16
+)
+
+// ErrBudgetUnreachable is returned when no quality or scale fits the byte budget
+var ErrBudgetUnreachable = errors.New("image cannot be compressed to the requested size")
+
+// EncodeResult holds the encoded image along with the settings that produced it
+type EncodeResult struct {
+	Data    []byte
+	Quality int
+	Passes  int
+
+	// Format is the format the data was encoded to, never FormatAuto
+	Format string
+
+	// SSIM and PSNR are only set when the output was measured against the source
+	SSIM float64
+	PSNR float64
+
+	// ColorConverted is set when the source was converted from its ICC profile to sRGB
+	ColorConverted bool
+}
+
+// EncodeWithinBudget searches the encoder quality, and if needed the scale,
+// until the encoded image fits in params.MaxBytes. Quality is used as the upper bound.
+func (p *ImageProcessor) EncodeWithinBudget(ctx context.Context, img image.Image, format string, params CompressionParams) (*EncodeResult, error) {
+	encode := func(q int) ([]byte, error) {
+		attempt := params
+		attempt.Quality = q
+		return p.EncodeToFormat(img, format, attempt)
+	}
+	shrink := func() bool {
+		scaled, ok := shrinkForBudget(img, params.Kernel)
+		if ok {
+			img = scaled
+		}
+		return ok
+	}
+	return searchBudget(ctx, format, params, encode, shrink)
+}
+
+// searchBudget searches the quality, then shrinks the image one step at a
+// time and searches again, until encode fits in params.MaxBytes. encode
+// encodes at a quality and shrink scales the image down, reporting false
+// once it would become too small.
+func searchBudget(ctx context.Context, format string, params CompressionParams, encode func(q int) ([]byte, error), shrink func() bool) (*EncodeResult, error) {
+	result := &EncodeResult{}
+
+	for {
+		data, q, err := searchQuality(ctx, format, params, encode, &result.Passes)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			result.Data = data
+			result.Quality = q
+			return result, nil
+		}
+
+		// Nothing fits at this size, shrink the image and try again
+		if !shrink() {
+			return nil, fmt.Errorf("%w: %d bytes after %d passes", ErrBudgetUnreachable, params.MaxBytes, result.Passes)
+		}
+	}
+}
+
+// shrinkForBudget scales img down by budgetScaleStep, or reports false when
+// that would take it below minBudgetDimension
+func shrinkForBudget(img image.Image, kernel Kernel) (image.Image, bool) {
+	bounds := img.Bounds()
+	newWidth := int(float64(bounds.Dx()) * budgetScaleStep)
+	newHeight := int(float64(bounds.Dy()) * budgetScaleStep)
+	if newWidth < minBudgetDimension || newHeight < minBudgetDimension {
+		return nil, false
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	scaleInto(scaled, scaled.Bounds(), img, bounds, kernel)
+	return scaled, true
+}
+
+// searchQuality binary-searches the highest quality that fits in params.MaxBytes.
+// It returns nil data when even the lowest quality is too large.
+func searchQuality(ctx context.Context, format string, params CompressionParams, encodeAt func(q int) ([]byte, error), passes *int) ([]byte, int, error) {
+	maxQuality, maxBytes := params.Quality, params.MaxBytes
+	encode := func(q int) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		*passes++
+		return encodeAt(q)
+	}
+
+	// Try the requested quality first, most images already fit
+	data, err := encode(maxQuality)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) <= maxBytes {
+		return data, maxQuality, nil
+	}
+
+	// PNG and lossless WebP ignore quality, so only scaling can help
+	if !hasQualitySetting(format, params) {
+		return nil, 0, nil
+	}
+
+	var best []byte
+	bestQuality := 0
+	lo, hi := minSearchQuality, maxQuality-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		data, err := encode(mid)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(data) <= maxBytes {
+			best, bestQuality = data, mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return best, bestQuality, nil
+}
+
+// hasQualitySetting reports whether the encoder for format uses the quality
+func hasQualitySetting(format string, params CompressionParams) bool {
+	switch format {
+	case "webp":
+		return !params.Lossless && params.NearLosslessStrength == 0
+	case "jpeg", "jpg":
+		return true
+	default:
+		return false
+	}
+}
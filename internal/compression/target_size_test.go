@@ -0,0 +1,130 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/metadata"
+)
+
+// noiseImage builds an image that doesn't compress well, so quality matters
+func noiseImage(width, height int) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255})
+		}
+	}
+	return img
+}
+
+func TestEncodeWithinBudget(t *testing.T) {
+	processor := NewImageProcessor()
+	img := noiseImage(64, 64)
+
+	full, err := processor.EncodeToFormat(img, "jpeg", CompressionParams{Quality: 90})
+	if err != nil {
+		t.Fatalf("EncodeToFormat: %v", err)
+	}
+
+	// A budget the requested quality already fits takes one pass
+	result, err := processor.EncodeWithinBudget(context.Background(), img, "jpeg", CompressionParams{Quality: 90, MaxBytes: len(full)})
+	if err != nil {
+		t.Fatalf("EncodeWithinBudget(roomy): %v", err)
+	}
+	if result.Quality != 90 || result.Passes != 1 {
+		t.Errorf("roomy budget: quality %d after %d passes, want 90 after 1", result.Quality, result.Passes)
+	}
+
+	// A tighter budget lowers the quality
+	budget := len(full) / 2
+	result, err = processor.EncodeWithinBudget(context.Background(), img, "jpeg", CompressionParams{Quality: 90, MaxBytes: budget})
+	if err != nil {
+		t.Fatalf("EncodeWithinBudget(tight): %v", err)
+	}
+	if len(result.Data) > budget || result.Quality >= 90 || result.Quality < minSearchQuality {
+		t.Errorf("tight budget: %d bytes at quality %d, want at most %d bytes below quality 90", len(result.Data), result.Quality, budget)
+	}
+}
+
+func TestEncodeWithinBudgetDownscales(t *testing.T) {
+	processor := NewImageProcessor()
+	img := noiseImage(64, 64)
+
+	// PNG ignores quality, so only a smaller image fits
+	full, err := processor.EncodeToFormat(img, "png", CompressionParams{})
+	if err != nil {
+		t.Fatalf("EncodeToFormat: %v", err)
+	}
+	budget := len(full) / 2
+
+	result, err := processor.EncodeWithinBudget(context.Background(), img, "png", CompressionParams{Quality: 80, MaxBytes: budget, Kernel: KernelNearest})
+	if err != nil {
+		t.Fatalf("EncodeWithinBudget: %v", err)
+	}
+	if len(result.Data) > budget {
+		t.Errorf("output is %d bytes, over the %d byte budget", len(result.Data), budget)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(result.Data))
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if config.Width >= 64 || config.Height >= 64 {
+		t.Errorf("output is %dx%d, want it downscaled", config.Width, config.Height)
+	}
+}
+
+func TestEncodeWithinBudgetUnreachable(t *testing.T) {
+	processor := NewImageProcessor()
+
+	_, err := processor.EncodeWithinBudget(context.Background(), noiseImage(64, 64), "jpeg", CompressionParams{Quality: 80, MaxBytes: 10})
+	if !errors.Is(err, ErrBudgetUnreachable) {
+		t.Errorf("err = %v, want ErrBudgetUnreachable", err)
+	}
+}
+
+func TestProcessImageBudgetCountsMetadataFraming(t *testing.T) {
+	processor := NewImageProcessor()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, noiseImage(64, 64), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("encoding source: %v", err)
+	}
+	xmp := []byte("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">" + strings.Repeat(" ", 2000) + "</x:xmpmeta>")
+	source, err := metadata.Inject(buf.Bytes(), "jpeg", &metadata.Metadata{XMP: xmp})
+	if err != nil {
+		t.Fatalf("injecting XMP: %v", err)
+	}
+
+	// The XMP segment's marker and namespace header come on top of the XMP itself
+	fitted := 0
+	for budget := 3000; budget <= 6000; budget += 250 {
+		params := CompressionParams{Quality: 90, MaxBytes: budget, Metadata: metadata.PolicyKeep}
+		result, err := processor.ProcessImage(context.Background(), bytes.NewReader(source), "jpeg", params, processor.GetDefaultAlgorithm())
+		if errors.Is(err, ErrBudgetUnreachable) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ProcessImage(%d): %v", budget, err)
+		}
+		fitted++
+		if len(result.Data) > budget {
+			t.Errorf("output with metadata is %d bytes, over the %d byte budget", len(result.Data), budget)
+		}
+		if extracted := metadata.Extract(result.Data); !bytes.Equal(extracted.XMP, xmp) {
+			t.Errorf("budget %d: XMP not kept", budget)
+		}
+	}
+	if fitted == 0 {
+		t.Error("no budget could be met")
+	}
+}
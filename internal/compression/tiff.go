@@ -0,0 +1,116 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"golang.org/x/image/tiff"
+)
+
+// maxTIFFPages bounds the IFD walk so a looping chain cannot hang a request
+const maxTIFFPages = 1024
+
+// isTIFF reports whether data starts with a classic little- or big-endian TIFF header
+func isTIFF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+}
+
+// TIFFPageCount returns the number of pages in a TIFF file, or 0 if data is not a TIFF
+func TIFFPageCount(data []byte) int {
+	return len(tiffPageOffsets(data))
+}
+
+// tiffPageOffsets follows the IFD chain and returns the offset of every page
+func tiffPageOffsets(data []byte) []uint32 {
+	if !isTIFF(data) || len(data) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	var offsets []uint32
+	seen := make(map[uint32]bool)
+	offset := order.Uint32(data[4:8])
+	for offset != 0 && len(offsets) < maxTIFFPages && !seen[offset] {
+		if int64(offset)+2 > int64(len(data)) {
+			break
+		}
+		seen[offset] = true
+		offsets = append(offsets, offset)
+
+		entries := int64(order.Uint16(data[offset : offset+2]))
+		next := int64(offset) + 2 + entries*12
+		if next+4 > int64(len(data)) {
+			break
+		}
+		offset = order.Uint32(data[next : next+4])
+	}
+
+	return offsets
+}
+
+// decodeTIFFPage decodes one page of a TIFF. The tiff package only reads the
+// first IFD, so later pages are decoded through a view of the file whose
+// header points at that page instead.
+func decodeTIFFPage(data []byte, page int) (image.Image, error) {
+	view, err := tiffPageView(data, page)
+	if err != nil {
+		return nil, err
+	}
+	return tiff.Decode(view)
+}
+
+// decodeTIFFPageConfig reads the dimensions of one page of a TIFF
+func decodeTIFFPageConfig(data []byte, page int) (image.Config, error) {
+	view, err := tiffPageView(data, page)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return tiff.DecodeConfig(view)
+}
+
+// tiffPageView returns a reader over data whose header points at the given page
+func tiffPageView(data []byte, page int) (*io.SectionReader, error) {
+	offsets := tiffPageOffsets(data)
+	if page < 0 || page >= len(offsets) {
+		return nil, fmt.Errorf("tiff has %d pages, page %d requested (pages count from 0)", len(offsets), page)
+	}
+
+	var header [8]byte
+	copy(header[:], data[:8])
+	if data[0] == 'M' {
+		binary.BigEndian.PutUint32(header[4:], offsets[page])
+	} else {
+		binary.LittleEndian.PutUint32(header[4:], offsets[page])
+	}
+
+	view := &headerOverlay{data: data, header: header}
+	return io.NewSectionReader(view, 0, int64(len(data))), nil
+}
+
+// headerOverlay reads data with its first 8 bytes replaced
+type headerOverlay struct {
+	data   []byte
+	header [8]byte
+}
+
+func (h *headerOverlay) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(h.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, h.data[off:])
+	if off < int64(len(h.header)) {
+		copy(p, h.header[off:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
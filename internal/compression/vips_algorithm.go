@@ -1,56 +1,275 @@
+//go:build vips
+
 package compression
 
-// import (
-// 	"bytes"
-// 	"image"
-// 	"image/png"
-
-// 	"github.com/davidbyttow/govips/v2/vips"
-// )
-
-// type VipsAlgorithm struct{}
-
-// func NewVipsAlgorithm() *VipsAlgorithm {
-// 	vips.Startup(nil)
-// 	return &VipsAlgorithm{}
-// }
-
-// func (a *VipsAlgorithm) Name() string {
-// 	return "vips"
-// }
-
-// func (a *VipsAlgorithm) CompressImage(img image.Image, params CompressionParams) image.Image {
-// 	// Encode input image to buffer (WebP encoder expects raw image bytes)
-// 	var inputBuf bytes.Buffer
-// 	_ = png.Encode(&inputBuf, img) // fallback to PNG as common baseline
-
-// 	// Load image with libvips
-// 	vipsImage, err := vips.NewImageFromBuffer(inputBuf.Bytes())
-// 	if err != nil {
-// 		return img // fallback: return original
-// 	}
-// 	defer vipsImage.Close()
-
-// 	// Determine scale factor from quality
-// 	scale := float64(params.Quality) / 100.0
-// 	if scale <= 0.0 {
-// 		scale = 1.0
-// 	}
-
-// 	_ = vipsImage.Resize(scale, vips.KernelNearest)
-
-// 	webpBuf, _, err := vipsImage.ExportWebp(&vips.WebpExportParams{
-// 		Quality:        params.Quality,
-// 		StripMetadata:  true,
-// 	})
-// 	if err != nil {
-// 		return img
-// 	}
-
-// 	// Decode final image for Go pipeline (must return image.Image)
-// 	finalImg, _, err := image.Decode(bytes.NewReader(webpBuf))
-// 	if err != nil {
-// 		return img
-// 	}
-// 	return finalImg
-// }
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/davidbyttow/govips/v2/vips"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/jpegenc"
+)
+
+func init() {
+	optionalAlgorithms = append(optionalAlgorithms, func() Algorithm {
+		algorithm, err := NewVipsAlgorithm()
+		if err != nil {
+			log.Printf("vips algorithm disabled: %v", err)
+			return nil
+		}
+		return algorithm
+	})
+}
+
+var (
+	// vipsStartup starts libvips once per process
+	vipsStartup sync.Once
+	vipsErr     error
+)
+
+// startVips starts libvips, turning the panic govips raises when the library
+// can't start into an error
+func startVips() error {
+	vipsStartup.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				vipsErr = fmt.Errorf("starting libvips: %v", r)
+			}
+		}()
+		vips.LoggingSettings(nil, vips.LogLevelError)
+		vips.Startup(nil)
+	})
+	return vipsErr
+}
+
+// VipsAlgorithm decodes, resizes and encodes WebP, JPEG and PNG with libvips,
+// straight from the encoded input. Other formats, byte budgets and images
+// that need ICC conversion go through the Go pipeline, resized like the
+// scale algorithm.
+type VipsAlgorithm struct{}
+
+func NewVipsAlgorithm() (*VipsAlgorithm, error) {
+	if err := startVips(); err != nil {
+		return nil, err
+	}
+	return &VipsAlgorithm{}, nil
+}
+
+func (a *VipsAlgorithm) Name() string {
+	return "vips"
+}
+
+func (a *VipsAlgorithm) Process(ctx context.Context, img image.Image, params CompressionParams) (image.Image, error) {
+	return NewScaleAlgorithm().Process(ctx, img, params)
+}
+
+// EncodeSource loads the input into libvips, applies the EXIF orientation and
+// the requested resize there, and exports format without the pixels ever
+// passing through Go
+func (a *VipsAlgorithm) EncodeSource(ctx context.Context, source []byte, format string, params CompressionParams) (*EncodeResult, bool, error) {
+	if !vipsExports(format, params) {
+		return nil, false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	importParams := vips.NewImportParams()
+	if isTIFF(source) {
+		importParams.Page.Set(params.Page)
+	}
+	ref, err := vips.LoadImageFromBuffer(source, importParams)
+	if err != nil {
+		return nil, false, fmt.Errorf("error loading image into libvips: %v", err)
+	}
+	defer ref.Close()
+
+	if !params.DisableAutoOrient {
+		if err := ref.AutoRotate(); err != nil {
+			return nil, false, fmt.Errorf("error orienting image with libvips: %v", err)
+		}
+	}
+
+	if err := vipsResize(ref, params); err != nil {
+		return nil, false, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	var data []byte
+	switch format {
+	case "webp":
+		export := vips.NewWebpExportParams()
+		export.StripMetadata = true
+		export.Quality = params.Quality
+		export.Lossless = params.Lossless || params.NearLosslessStrength > 0
+		export.NearLossless = params.NearLosslessStrength > 0
+		if export.NearLossless {
+			// libvips reads Q as the near-lossless level in this mode
+			export.Quality = MaxNearLossless - params.NearLosslessStrength
+		}
+		data, _, err = ref.ExportWebp(export)
+	case "jpeg", "jpg":
+		export := vips.NewJpegExportParams()
+		export.StripMetadata = true
+		export.Quality = params.Quality
+		export.Interlace = params.Progressive
+		export.OptimizeCoding = params.OptimizeHuffman
+		// Auto would turn subsampling off at quality 90 and up, 4:2:0 is the default here
+		export.SubsampleMode = vips.VipsForeignSubsampleOn
+		if params.ChromaSubsampling == jpegenc.Subsample444 {
+			export.SubsampleMode = vips.VipsForeignSubsampleOff
+		}
+		data, _, err = ref.ExportJpeg(export)
+	case "png":
+		export := vips.NewPngExportParams()
+		export.StripMetadata = true
+		export.Compression = vipsPNGCompression(params.PNGCompression)
+		if params.Colors > 0 {
+			export.Palette = true
+			export.Bitdepth = paletteBitDepth(params.Colors)
+			export.Dither = 1
+			if params.DisableDither {
+				export.Dither = 0
+			}
+		}
+		data, _, err = ref.ExportPng(export)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error encoding image to %s with libvips: %v", format, err)
+	}
+
+	return &EncodeResult{
+		Data:    data,
+		Quality: params.Quality,
+		Passes:  1,
+	}, true, nil
+}
+
+// vipsResize applies the resize requested in params the way Resize does,
+// including fit modes and gravity, and refuses outputs over the same limits
+func vipsResize(ref *vips.ImageRef, params CompressionParams) error {
+	width, height := ref.Width(), ref.Height()
+	outputWidth, outputHeight := outputDimensions(width, height, params)
+	if err := checkOutputSize(outputWidth, outputHeight, params); err != nil {
+		return err
+	}
+	kernel := vipsKernel(params.Kernel)
+
+	// scaleTo resamples to exactly w x h
+	scaleTo := func(w, h int) error {
+		if w == ref.Width() && h == ref.Height() {
+			return nil
+		}
+		if err := ref.ResizeWithVScale(float64(w)/float64(ref.Width()), float64(h)/float64(ref.Height()), kernel); err != nil {
+			return fmt.Errorf("error resizing image with libvips: %v", err)
+		}
+		return nil
+	}
+
+	boxed := params.Width > 0 && params.Height > 0
+	if !boxed || params.Fit == FitFill || params.Fit == FitInside || params.Fit == FitOutside {
+		return scaleTo(outputWidth, outputHeight)
+	}
+
+	scaleX := float64(params.Width) / float64(width)
+	scaleY := float64(params.Height) / float64(height)
+
+	if params.Fit == FitContain {
+		scaledWidth, scaledHeight := scaledSize(float64(width), float64(height), math.Min(scaleX, scaleY))
+		if err := scaleTo(scaledWidth, scaledHeight); err != nil {
+			return err
+		}
+
+		background := params.Background
+		if background == nil {
+			background = DefaultBackground
+		}
+		c := color.NRGBAModel.Convert(background).(color.NRGBA)
+
+		offsetX, offsetY := gravityOffset(params.Gravity, params.Width-scaledWidth, params.Height-scaledHeight)
+		if err := ref.EmbedBackgroundRGBA(offsetX, offsetY, params.Width, params.Height,
+			&vips.ColorRGBA{R: c.R, G: c.G, B: c.B, A: c.A}); err != nil {
+			return fmt.Errorf("error letterboxing image with libvips: %v", err)
+		}
+		return nil
+	}
+
+	// Cover: scale to cover the box, then crop the overflow by gravity
+	scaledWidth, scaledHeight := scaledSize(float64(width), float64(height), math.Max(scaleX, scaleY))
+	scaledWidth, scaledHeight = max(scaledWidth, params.Width), max(scaledHeight, params.Height)
+	if err := scaleTo(scaledWidth, scaledHeight); err != nil {
+		return err
+	}
+	offsetX, offsetY := gravityOffset(params.Gravity, ref.Width()-params.Width, ref.Height()-params.Height)
+	if err := ref.ExtractArea(offsetX, offsetY, params.Width, params.Height); err != nil {
+		return fmt.Errorf("error cropping image with libvips: %v", err)
+	}
+	return nil
+}
+
+// vipsKernel maps a resampling kernel onto the closest libvips kernel
+func vipsKernel(kernel Kernel) vips.Kernel {
+	switch kernel {
+	case KernelNearest:
+		return vips.KernelNearest
+	case KernelCatmullRom:
+		return vips.KernelCubic
+	case KernelLanczos:
+		return vips.KernelLanczos3
+	default:
+		return vips.KernelLinear
+	}
+}
+
+// vipsExports reports whether libvips can produce format with the requested
+// settings; 4:2:2 JPEG and exact transparent pixels are left to the Go encoders
+func vipsExports(format string, params CompressionParams) bool {
+	switch format {
+	case "webp":
+		return !params.Exact
+	case "jpeg", "jpg":
+		return params.ChromaSubsampling != jpegenc.Subsample422
+	case "png":
+		return true
+	default:
+		return false
+	}
+}
+
+// vipsPNGCompression maps a zlib effort onto the libvips 0-9 scale
+func vipsPNGCompression(level png.CompressionLevel) int {
+	switch level {
+	case png.NoCompression:
+		return 0
+	case png.BestSpeed:
+		return 1
+	case png.BestCompression:
+		return 9
+	default:
+		return 6
+	}
+}
+
+// paletteBitDepth is the smallest PNG bit depth holding a palette of n colors
+func paletteBitDepth(n int) int {
+	switch {
+	case n <= 2:
+		return 1
+	case n <= 4:
+		return 2
+	case n <= 16:
+		return 4
+	default:
+		return 8
+	}
+}
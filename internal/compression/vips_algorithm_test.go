@@ -0,0 +1,108 @@
+//go:build vips
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// newTestVipsAlgorithm skips the test when libvips is not available
+func newTestVipsAlgorithm(t *testing.T) *VipsAlgorithm {
+	t.Helper()
+
+	algorithm, err := NewVipsAlgorithm()
+	if err != nil {
+		t.Skipf("libvips not available: %v", err)
+	}
+	return algorithm
+}
+
+func TestVipsAlgorithmIsRegistered(t *testing.T) {
+	newTestVipsAlgorithm(t)
+
+	if _, ok := NewImageProcessor().GetAlgorithm("vips"); !ok {
+		t.Error("vips algorithm not registered")
+	}
+}
+
+func TestVipsEncodeSourceResizes(t *testing.T) {
+	algorithm := newTestVipsAlgorithm(t)
+	source := encodeTestPNG(t, 200, 100)
+
+	tests := []struct {
+		name          string
+		params        CompressionParams
+		width, height int
+	}{
+		{"scale", CompressionParams{Scale: 0.5}, 100, 50},
+		{"width only", CompressionParams{Width: 50}, 50, 25},
+		{"cover", CompressionParams{Width: 40, Height: 40, Fit: FitCover}, 40, 40},
+		{"contain", CompressionParams{Width: 40, Height: 40, Fit: FitContain}, 40, 40},
+		{"inside", CompressionParams{Width: 40, Height: 40, Fit: FitInside}, 40, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Quality = 80
+			for _, format := range []string{"jpeg", "png", "webp"} {
+				result, handled, err := algorithm.EncodeSource(context.Background(), source, format, tt.params)
+				if err != nil || !handled {
+					t.Fatalf("EncodeSource(%s) = handled %v, err %v", format, handled, err)
+				}
+
+				config, _, err := image.DecodeConfig(bytes.NewReader(result.Data))
+				if err != nil {
+					t.Fatalf("decoding %s output: %v", format, err)
+				}
+				if config.Width != tt.width || config.Height != tt.height {
+					t.Errorf("%s output is %dx%d, want %dx%d", format, config.Width, config.Height, tt.width, tt.height)
+				}
+			}
+		})
+	}
+}
+
+func TestVipsEncodeSourceRefusesOversizedOutput(t *testing.T) {
+	algorithm := newTestVipsAlgorithm(t)
+
+	params := CompressionParams{Quality: 80, Scale: 10, MaxPixels: 1000}
+	if _, _, err := algorithm.EncodeSource(context.Background(), encodeTestPNG(t, 20, 20), "jpeg", params); err == nil {
+		t.Error("output over the pixel limit was produced")
+	}
+}
+
+func TestVipsEncodeSourceLeavesUnsupportedSettings(t *testing.T) {
+	algorithm := newTestVipsAlgorithm(t)
+
+	_, handled, err := algorithm.EncodeSource(context.Background(), encodeTestPNG(t, 20, 20), "gif", CompressionParams{Quality: 80})
+	if err != nil || handled {
+		t.Errorf("EncodeSource(gif) = handled %v, err %v; want it left to the Go encoders", handled, err)
+	}
+}
+
+func TestVipsNearLosslessUsesTheRequestedStrength(t *testing.T) {
+	algorithm := newTestVipsAlgorithm(t)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, noiseImage(64, 64)); err != nil {
+		t.Fatalf("encoding source: %v", err)
+	}
+
+	size := func(strength int) int {
+		params := CompressionParams{Quality: 80, NearLosslessStrength: strength}
+		result, handled, err := algorithm.EncodeSource(context.Background(), buf.Bytes(), "webp", params)
+		if err != nil || !handled {
+			t.Fatalf("EncodeSource(near_lossless %d) = handled %v, err %v", strength, handled, err)
+		}
+		return len(result.Data)
+	}
+
+	// The lossy quality must not stand in for the near-lossless level
+	if weak, strong := size(10), size(MaxNearLossless); strong >= weak {
+		t.Errorf("strength %d gave %d bytes, not less than strength 10's %d", MaxNearLossless, strong, weak)
+	}
+}
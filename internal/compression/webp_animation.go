@@ -0,0 +1,141 @@
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/riff"
+)
+
+// ANMF flags: bit 1 turns blending off, bit 0 disposes the frame to background
+const anmfNoBlend = 0x02
+
+// maxWebPDuration is the longest frame duration an ANMF chunk can hold
+const maxWebPDuration = 1<<24 - 1
+
+// encodeAnimatedWebP encodes each frame with the still-image encoder and muxes
+// the bitstreams into an animated WebP. Frames are full canvases drawn without
+// blending, so every frame replaces the last exactly like the composited source.
+func encodeAnimatedWebP(w io.Writer, animation *Animation, params CompressionParams) error {
+	if len(animation.Frames) == 0 {
+		return fmt.Errorf("animation has no frames")
+	}
+
+	canvas := animation.Frames[0].Bounds()
+	var flags byte = riff.VP8XFlagAnimation
+	var frames bytes.Buffer
+
+	for i, frame := range animation.Frames {
+		var still bytes.Buffer
+		if err := encodeWebP(&still, frame, params); err != nil {
+			return err
+		}
+
+		bitstream, alpha, err := webpBitstream(still.Bytes())
+		if err != nil {
+			return fmt.Errorf("frame %d: %v", i, err)
+		}
+		if alpha {
+			flags |= riff.VP8XFlagAlpha
+		}
+
+		bounds := frame.Bounds()
+		header := make([]byte, 16)
+		riff.PutUint24(header[6:9], uint32(bounds.Dx()-1))
+		riff.PutUint24(header[9:12], uint32(bounds.Dy()-1))
+		riff.PutUint24(header[12:15], webpDuration(animation.Delays[i]))
+		header[15] = anmfNoBlend
+
+		riff.WriteChunk(&frames, "ANMF", append(header, bitstream...))
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	riff.PutUint24(vp8x[4:7], uint32(canvas.Dx()-1))
+	riff.PutUint24(vp8x[7:10], uint32(canvas.Dy()-1))
+
+	// Transparent background, then the loop count
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint16(anim[4:6], webpLoopCount(animation.LoopCount))
+
+	var out bytes.Buffer
+	out.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	riff.WriteChunk(&out, "VP8X", vp8x)
+	riff.WriteChunk(&out, "ANIM", anim)
+	out.Write(frames.Bytes())
+
+	data := out.Bytes()
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	_, err := w.Write(data)
+	return err
+}
+
+// webpBitstream returns the ALPH, VP8 and VP8L chunks of a still WebP as they
+// appear inside an ANMF frame, and whether the frame carries alpha
+func webpBitstream(data []byte) ([]byte, bool, error) {
+	if !riff.IsWebP(data) {
+		return nil, false, fmt.Errorf("not a webp file")
+	}
+
+	var bitstream bytes.Buffer
+	alpha := false
+	for pos := 12; pos+8 <= len(data); {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		if pos+8+size > len(data) {
+			return nil, false, fmt.Errorf("truncated %q chunk", fourCC)
+		}
+		payload := data[pos+8 : pos+8+size]
+
+		switch fourCC {
+		case "ALPH":
+			alpha = true
+			riff.WriteChunk(&bitstream, fourCC, payload)
+		case "VP8 ":
+			riff.WriteChunk(&bitstream, fourCC, payload)
+		case "VP8L":
+			// The alpha hint sits in bit 28 after the signature byte
+			if len(payload) >= 5 && (binary.LittleEndian.Uint32(payload[1:5])>>28)&1 == 1 {
+				alpha = true
+			}
+			riff.WriteChunk(&bitstream, fourCC, payload)
+		}
+
+		pos += 8 + size + size%2
+	}
+
+	if bitstream.Len() == 0 {
+		return nil, false, fmt.Errorf("no image data")
+	}
+	return bitstream.Bytes(), alpha, nil
+}
+
+// webpDuration converts a frame delay to whole milliseconds
+func webpDuration(delay time.Duration) uint32 {
+	ms := delay.Milliseconds()
+	if ms < 0 {
+		return 0
+	}
+	if ms > maxWebPDuration {
+		return maxWebPDuration
+	}
+	return uint32(ms)
+}
+
+// webpLoopCount converts a GIF loop count, where n means n extra plays, to
+// WebP where it is the total number of plays and 0 still means forever
+func webpLoopCount(loopCount int) uint16 {
+	switch {
+	case loopCount == 0:
+		return 0
+	case loopCount < 0:
+		return 1
+	case loopCount >= 0xFFFF:
+		return 0xFFFF
+	default:
+		return uint16(loopCount + 1)
+	}
+}
@@ -0,0 +1,121 @@
+package compression
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+)
+
+const (
+	// MaxNearLossless is the top of the near-lossless scale: the strongest
+	// preprocessing strength, and the libwebp level that turns it off
+	MaxNearLossless = 100
+
+	// maxNearLosslessBits is how many low bits the strongest level may drop
+	maxNearLosslessBits = 5
+)
+
+// NearLosslessStrength converts a libwebp near-lossless level, where 100 is
+// off and 0 the strongest, to the strength in CompressionParams
+func NearLosslessStrength(level int) int {
+	return MaxNearLossless - level
+}
+
+// encodeWebP writes img as WebP using the lossy, lossless or near-lossless
+// settings in params
+func encodeWebP(w io.Writer, img image.Image, params CompressionParams) error {
+	lossless := params.Lossless || params.NearLosslessStrength > 0
+	exact := params.Exact && lossless
+
+	mode := "lossy"
+	switch {
+	case params.NearLosslessStrength > 0:
+		mode = "near_lossless"
+		img = nearLossless(img, params.NearLosslessStrength)
+	case lossless:
+		mode = "lossless"
+	}
+
+	err := webp.Encode(w, webpSource(img), &webp.Options{
+		Lossless: lossless,
+		Quality:  float32(params.Quality),
+		Exact:    exact,
+	})
+	if err == nil {
+		metrics.RecordWebPEncode(mode, exact)
+	}
+	return err
+}
+
+// webpSource prepares pixels for libwebp, which expects straight (not
+// premultiplied) alpha. The webp package hands *image.RGBA pixels over
+// unchanged, so images with transparency are passed as NRGBA pixels in an
+// RGBA header. This also keeps the color of fully transparent pixels intact
+// for exact encodes.
+func webpSource(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+
+	nrgba := toNRGBA(img)
+	return &image.RGBA{Pix: nrgba.Pix, Stride: nrgba.Stride, Rect: nrgba.Rect}
+}
+
+// nearLossless snaps the color channels of pixels that sit on edges or in
+// noise to a coarser grid, which makes the lossless encoder's job easier.
+// Smooth areas are left alone since banding shows there first. Alpha is never
+// changed. Strength runs from 1 to MaxNearLossless.
+func nearLossless(img image.Image, strength int) *image.NRGBA {
+	if strength > MaxNearLossless {
+		strength = MaxNearLossless
+	}
+	bits := (strength*maxNearLosslessBits + MaxNearLossless - 1) / MaxNearLossless
+	limit := 1 << bits
+
+	src := toNRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+
+	// Border pixels have no full neighbourhood and are kept as they are
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			i := y*src.Stride + x*4
+			if isSmooth(src, i, limit) {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				dst.Pix[i+c] = quantizeChannel(src.Pix[i+c], bits)
+			}
+		}
+	}
+
+	return dst
+}
+
+// isSmooth reports whether the four neighbours of the pixel at offset i are
+// all within limit of it on every channel
+func isSmooth(img *image.NRGBA, i int, limit int) bool {
+	for _, neighbour := range []int{i - 4, i + 4, i - img.Stride, i + img.Stride} {
+		for c := 0; c < 4; c++ {
+			diff := int(img.Pix[i+c]) - int(img.Pix[neighbour+c])
+			if diff >= limit || diff <= -limit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// quantizeChannel rounds v to the nearest multiple of 2^bits, staying in range
+func quantizeChannel(v uint8, bits int) uint8 {
+	step := 1 << bits
+	rounded := (int(v) + step/2) &^ (step - 1)
+	if rounded > 255 {
+		rounded = 255 &^ (step - 1)
+	}
+	return uint8(rounded)
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -34,14 +35,27 @@ type CompressionConfig struct {
 	DefaultAlgorithm       string
 	MaxUploadSize          int64
 	MaxBatchSize           int
+	MaxPixels              int64 // Largest width times height accepted for decoding
 	ImageProcessingTimeout time.Duration
 	BatchProcessingTimeout time.Duration
 }
 
 // WorkerConfig represents worker pool configuration
 type WorkerConfig struct {
-	WorkerCount  int
-	JobQueueSize int
+	WorkerCount   int
+	JobQueueSize  int
+	MaxQueueWait  time.Duration // Estimated queue wait above which requests are shed
+	MemoryBudget  int64         // Estimated decode memory running jobs may use together
+	TenantHeader  string        // Request header or gRPC metadata key naming the tenant
+	Tenants       map[string]TenantConfig
+	DefaultTenant TenantConfig // Limits of the one queue shared by tenants not listed in Tenants
+}
+
+// TenantConfig represents a tenant's share of the worker pool
+type TenantConfig struct {
+	Weight      int // Share of workers relative to other tenants
+	MaxInFlight int // Jobs running at once, 0 for no limit
+	MaxQueued   int // Jobs waiting at once, 0 for no limit
 }
 
 // MetricsConfig represents metrics configuration
@@ -56,6 +70,11 @@ type MetricsConfig struct {
 type ServiceConfig struct {
 	WorkerCount            int
 	JobQueueSize           int
+	MaxQueueWait           time.Duration
+	MemoryBudget           int64
+	TenantHeader           string
+	Tenants                map[string]TenantConfig
+	DefaultTenant          TenantConfig
 	DefaultQuality         int
 	DefaultFormat          string
 	DefaultAlgorithm       string
@@ -64,6 +83,7 @@ type ServiceConfig struct {
 	BatchProcessingTimeout time.Duration
 	MaxUploadSize          int64
 	MaxBatchSize           int
+	MaxPixels              int64
 }
 
 // CreateServiceConfig creates a ServiceConfig from AppConfig
@@ -71,6 +91,11 @@ func (c AppConfig) CreateServiceConfig() ServiceConfig {
 	return ServiceConfig{
 		WorkerCount:            c.Worker.WorkerCount,
 		JobQueueSize:           c.Worker.JobQueueSize,
+		MaxQueueWait:           c.Worker.MaxQueueWait,
+		MemoryBudget:           c.Worker.MemoryBudget,
+		TenantHeader:           c.Worker.TenantHeader,
+		Tenants:                c.Worker.Tenants,
+		DefaultTenant:          c.Worker.DefaultTenant,
 		DefaultQuality:         c.Compression.DefaultQuality,
 		DefaultFormat:          c.Compression.DefaultFormat,
 		DefaultAlgorithm:       c.Compression.DefaultAlgorithm,
@@ -79,6 +104,7 @@ func (c AppConfig) CreateServiceConfig() ServiceConfig {
 		BatchProcessingTimeout: c.Compression.BatchProcessingTimeout,
 		MaxUploadSize:          c.Compression.MaxUploadSize,
 		MaxBatchSize:           c.Compression.MaxBatchSize,
+		MaxPixels:              c.Compression.MaxPixels,
 	}
 }
 
@@ -97,12 +123,22 @@ func LoadConfig() AppConfig {
 			DefaultAlgorithm:       getEnvWithDefault("DEFAULT_ALGORITHM", "scale"),
 			MaxUploadSize:          getInt64WithDefault("MAX_UPLOAD_SIZE", 32<<20), // 32 MB
 			MaxBatchSize:           getIntWithDefault("MAX_BATCH_SIZE", 50),
+			MaxPixels:              getInt64WithDefault("MAX_PIXELS", 100_000_000), // 100 megapixels
 			ImageProcessingTimeout: getDurationWithDefault("IMAGE_PROCESSING_TIMEOUT", 30*time.Second),
 			BatchProcessingTimeout: getDurationWithDefault("BATCH_PROCESSING_TIMEOUT", 5*time.Minute),
 		},
 		Worker: WorkerConfig{
 			WorkerCount:  getIntWithDefault("WORKER_COUNT", runtime.NumCPU()),
 			JobQueueSize: getIntWithDefault("JOB_QUEUE_SIZE", runtime.NumCPU()*4),
+			MaxQueueWait: getDurationWithDefault("MAX_QUEUE_WAIT", 10*time.Second),
+			MemoryBudget: getInt64WithDefault("MEMORY_BUDGET", 2<<30), // 2 GB
+			TenantHeader: getEnvWithDefault("TENANT_HEADER", "X-Tenant-ID"),
+			Tenants:      loadTenants(),
+			DefaultTenant: TenantConfig{
+				Weight:      getIntWithDefault("DEFAULT_TENANT_WEIGHT", 1),
+				MaxInFlight: getIntWithDefault("DEFAULT_TENANT_MAX_IN_FLIGHT", 0),
+				MaxQueued:   getIntWithDefault("DEFAULT_TENANT_MAX_QUEUED", 0),
+			},
 		},
 		Metrics: MetricsConfig{
 			Enabled:           getBoolWithDefault("METRICS_ENABLED", true),
@@ -117,6 +153,27 @@ func LoadConfig() AppConfig {
 	}
 }
 
+// loadTenants builds per-tenant limits from TENANT_WEIGHTS, TENANT_MAX_IN_FLIGHT
+// and TENANT_MAX_QUEUED, each a list like "acme=4,globex=1". Tenants missing
+// from a list take the default for that limit.
+func loadTenants() map[string]TenantConfig {
+	weights := getIntMap("TENANT_WEIGHTS")
+	maxInFlight := getIntMap("TENANT_MAX_IN_FLIGHT")
+	maxQueued := getIntMap("TENANT_MAX_QUEUED")
+
+	tenants := make(map[string]TenantConfig)
+	for _, values := range []map[string]int{weights, maxInFlight, maxQueued} {
+		for name := range values {
+			tenants[name] = TenantConfig{
+				Weight:      getIntFromMap(weights, name, getIntWithDefault("DEFAULT_TENANT_WEIGHT", 1)),
+				MaxInFlight: getIntFromMap(maxInFlight, name, getIntWithDefault("DEFAULT_TENANT_MAX_IN_FLIGHT", 0)),
+				MaxQueued:   getIntFromMap(maxQueued, name, getIntWithDefault("DEFAULT_TENANT_MAX_QUEUED", 0)),
+			}
+		}
+	}
+	return tenants
+}
+
 // Helper functions to get environment variables with defaults
 
 func getEnvWithDefault(key, defaultValue string) string {
@@ -180,4 +237,28 @@ func getDurationWithDefault(key string, defaultValue time.Duration) time.Duratio
 	}
 	
 	return value
-}
\ No newline at end of file
+}
+
+// getIntMap parses a list like "a=1,b=2", skipping malformed entries
+func getIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		name, strValue, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(strValue))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values
+}
+
+func getIntFromMap(values map[string]int, key string, defaultValue int) int {
+	if value, ok := values[key]; ok {
+		return value
+	}
+	return defaultValue
+}
@@ -3,16 +3,26 @@ package grpc
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/api"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/jpegenc"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/compression/metadata"
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
+	"github.com/teamleaderleo/potato-quality-image-compressor/internal/worker"
 	pb "github.com/teamleaderleo/potato-quality-image-compressor/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 // Adapter implements the gRPC server for image compression
@@ -53,6 +63,28 @@ func (a *Adapter) CompressImage(ctx context.Context, req *pb.CompressImageReques
 		metrics.GetRequestCounter().WithLabelValues("grpc-compress", status).Inc()
 	}()
 	
+	ctx, err := a.withScheduling(ctx)
+	if err != nil {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
+	params, err := paramsFromRequest(req)
+	if err == nil {
+		err = a.service.ValidateParams(&params)
+	}
+	if err != nil {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Reject formats no encoder produces before decoding anything
+	format, err := a.service.ValidateFormat(req.Format)
+	if err != nil {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Create an HTTP-like structure to reuse the service implementation
 	imgData := bytes.NewReader(req.ImageData)
 	
@@ -61,11 +93,21 @@ func (a *Adapter) CompressImage(ctx context.Context, req *pb.CompressImageReques
 		ctx,
 		req.Filename,
 		imgData,
-		string(req.Format),
-		int(req.Quality),
+		format,
+		params,
 		string(req.Strategy),
 	)
 	
+	if errors.Is(err, compression.ErrUnknownAlgorithm) || errors.Is(err, compression.ErrImageTooLarge) {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, worker.ErrOverloaded) {
+		status = "overloaded"
+		retryAfter := strconv.Itoa(int(a.service.RetryAfter().Seconds()))
+		grpc.SetHeader(ctx, grpcmetadata.Pairs("retry-after", retryAfter))
+		return nil, grpcstatus.Errorf(codes.ResourceExhausted, "%v, retry after %ss", err, retryAfter)
+	}
 	if err != nil {
 		return &pb.CompressImageResponse{
 			Error: err.Error(),
@@ -75,15 +117,91 @@ func (a *Adapter) CompressImage(ctx context.Context, req *pb.CompressImageReques
 	// Convert result to protobuf response
 	return &pb.CompressImageResponse{
 		ImageData:        result.Data,
-		Format:           req.Format,
+		Format:           result.Format,
 		OriginalSize:     int64(result.OriginalSize),
 		CompressedSize:   int64(result.CompressedSize),
 		CompressionRatio: result.CompressionRatio,
 		ProcessingTimeMs: result.ProcessingTime.Milliseconds(),
 		Filename:         req.Filename,
+		QualityUsed:      int32(result.QualityUsed),
+		EncodePasses:     int32(result.EncodePasses),
+		Ssim:             result.SSIM,
+		Psnr:             result.PSNR,
+		ColorConverted:   result.ColorConverted,
 	}, nil
 }
 
+// paramsFromRequest converts the compression settings of a protobuf request.
+// Unknown names and an invalid background color are errors like over HTTP.
+// Ranges, including the palette size, and lossless options are checked by
+// the service's ValidateParams.
+func paramsFromRequest(req *pb.CompressImageRequest) (compression.CompressionParams, error) {
+	params := compression.CompressionParams{
+		Quality:    int(req.Quality),
+		Width:      int(req.Width),
+		Height:     int(req.Height),
+		Scale:      req.Scale,
+		MaxBytes:   int(req.MaxBytes),
+		TargetSSIM: req.TargetSsim,
+
+		DisableAutoOrient: req.AutoOrient != nil && !*req.AutoOrient,
+		Page:              int(req.Page),
+
+		Lossless: req.Lossless,
+	}
+
+	if req.NearLossless != nil {
+		params.NearLosslessStrength = compression.NearLosslessStrength(int(*req.NearLossless))
+	}
+	params.Exact = req.Exact
+
+	level, err := compression.ParsePNGCompression(req.PngCompression)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("png_compression: %w", err)
+	}
+	params.PNGCompression = level
+	params.Colors = int(req.Colors)
+	params.DisableDither = req.Dither != nil && !*req.Dither
+
+	params.Progressive = req.Progressive
+	params.OptimizeHuffman = req.OptimizeHuffman
+	subsampling, err := jpegenc.ParseSubsampling(req.Subsampling)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("subsampling: %w", err)
+	}
+	params.ChromaSubsampling = subsampling
+
+	fit, err := compression.ParseFitMode(req.Fit)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("fit: %w", err)
+	}
+	params.Fit = fit
+	gravity, err := compression.ParseGravity(req.Gravity)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("gravity: %w", err)
+	}
+	params.Gravity = gravity
+	policy, err := metadata.ParsePolicy(req.Metadata)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("metadata: %w", err)
+	}
+	params.Metadata = policy
+	kernel, err := compression.ParseKernel(req.Kernel)
+	if err != nil {
+		return compression.CompressionParams{}, fmt.Errorf("kernel: %w", err)
+	}
+	params.Kernel = kernel
+	if req.Background != "" {
+		background, err := compression.ParseColor(req.Background)
+		if err != nil {
+			return compression.CompressionParams{}, fmt.Errorf("background: %w", err)
+		}
+		params.Background = background
+	}
+
+	return params, nil
+}
+
 // BatchCompressImages handles multiple image compression requests
 func (a *Adapter) BatchCompressImages(ctx context.Context, req *pb.BatchCompressRequest) (*pb.BatchCompressResponse, error) {
 	timer := metrics.NewTimer("grpc-batch-compress")
@@ -101,21 +219,64 @@ func (a *Adapter) BatchCompressImages(ctx context.Context, req *pb.BatchCompress
 			Responses: []*pb.CompressImageResponse{},
 		}, nil
 	}
+
+	ctx, err := a.withScheduling(ctx)
+	if err != nil {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
 	
-	// Convert protobuf requests to BatchRequest objects
-	batchRequests := make([]api.BatchRequest, len(req.Requests))
-	for i, protoReq := range req.Requests {
-		batchRequests[i] = api.BatchRequest{
-			Filename:  protoReq.Filename,
-			Data:      bytes.NewReader(protoReq.ImageData),
-			Format:    string(protoReq.Format),
-			Quality:   int(protoReq.Quality),
-			Algorithm: string(protoReq.Strategy),
+	if err := a.service.CheckBatchSize(len(req.Requests)); err != nil {
+		status = "invalid_argument"
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Convert protobuf requests to BatchRequest objects, one per TIFF page
+	batchRequests := make([]api.BatchRequest, 0, len(req.Requests))
+	for _, protoReq := range req.Requests {
+		if err := a.service.ValidateAlgorithm(protoReq.Strategy); err != nil {
+			status = "invalid_argument"
+			return nil, grpcstatus.Errorf(codes.InvalidArgument, "%s: %v", protoReq.Filename, err)
+		}
+		params, err := paramsFromRequest(protoReq)
+		if err == nil {
+			err = a.service.ValidateParams(&params)
+		}
+		if err != nil {
+			status = "invalid_argument"
+			return nil, grpcstatus.Errorf(codes.InvalidArgument, "%s: %v", protoReq.Filename, err)
+		}
+		format, err := a.service.ValidateFormat(protoReq.Format)
+		if err != nil {
+			status = "invalid_argument"
+			return nil, grpcstatus.Errorf(codes.InvalidArgument, "%s: %v", protoReq.Filename, err)
+		}
+
+		batchRequests = append(batchRequests, api.NewBatchRequests(
+			protoReq.Filename,
+			protoReq.ImageData,
+			format,
+			params,
+			string(protoReq.Strategy),
+		)...)
+
+		// Multi-page TIFFs expand to a request per page, which counts against the limit too
+		if err := a.service.CheckBatchSize(len(batchRequests)); err != nil {
+			status = "invalid_argument"
+			return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
 		}
 	}
 	
 	// Process using the unified batch processor
 	batchResponse := a.service.ProcessBatchRequests(ctx, batchRequests)
+
+	// Shed images would otherwise go missing from an OK response
+	if batchResponse.Overloaded() {
+		status = "overloaded"
+		retryAfter := strconv.Itoa(int(a.service.RetryAfter().Seconds()))
+		grpc.SetHeader(ctx, grpcmetadata.Pairs("retry-after", retryAfter))
+		return nil, grpcstatus.Errorf(codes.ResourceExhausted, "%v, retry after %ss", worker.ErrOverloaded, retryAfter)
+	}
 	
 	// Convert results to protobuf responses
 	responses := make([]*pb.CompressImageResponse, 0, len(batchResponse.Results))
@@ -128,6 +289,11 @@ func (a *Adapter) BatchCompressImages(ctx context.Context, req *pb.BatchCompress
 			CompressionRatio: result.CompressionRatio,
 			ProcessingTimeMs: result.ProcessingTime.Milliseconds(),
 			Filename:         result.Filename,
+			QualityUsed:      int32(result.QualityUsed),
+			EncodePasses:     int32(result.EncodePasses),
+			Ssim:             result.SSIM,
+			Psnr:             result.PSNR,
+			ColorConverted:   result.ColorConverted,
 		}
 		responses = append(responses, resp)
 	}
@@ -152,6 +318,27 @@ func (a *Adapter) BatchCompressImages(ctx context.Context, req *pb.BatchCompress
 	}, nil
 }
 
+// withScheduling schedules the call's jobs for the tenant named by the tenant
+// metadata key and at the priority named by the "priority" key, if any
+func (a *Adapter) withScheduling(ctx context.Context) (context.Context, error) {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	if header := a.service.TenantHeader(); header != "" {
+		if tenants := md.Get(header); len(tenants) > 0 && tenants[0] != "" {
+			ctx = worker.WithTenant(ctx, tenants[0])
+		}
+	}
+
+	values := md.Get("priority")
+	if len(values) == 0 || values[0] == "" {
+		return ctx, nil
+	}
+	priority, err := worker.ParsePriority(values[0])
+	if err != nil {
+		return ctx, err
+	}
+	return worker.WithPriority(ctx, priority), nil
+}
+
 // StreamCompressImages handles streaming compression requests
 func (a *Adapter) StreamCompressImages(stream pb.ImageCompressionService_StreamCompressImagesServer) error {
 	for {
@@ -165,8 +352,11 @@ func (a *Adapter) StreamCompressImages(stream pb.ImageCompressionService_StreamC
 			return err
 		}
 		
-		// Process the image
-		resp, _ := a.CompressImage(stream.Context(), req)
+		// Process the image, ending the stream on request-level errors
+		resp, err := a.CompressImage(stream.Context(), req)
+		if err != nil {
+			return err
+		}
 		
 		// Send the response
 		if err := stream.Send(resp); err != nil {
@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/teamleaderleo/potato-quality-image-compressor/proto"
+)
+
+func TestParamsFromRequestRejectsUnknownNames(t *testing.T) {
+	tests := []struct {
+		field string
+		req   *pb.CompressImageRequest
+	}{
+		{"metadata", &pb.CompressImageRequest{Metadata: "copyrigth"}},
+		{"subsampling", &pb.CompressImageRequest{Subsampling: "4:1:1"}},
+		{"fit", &pb.CompressImageRequest{Fit: "contian"}},
+		{"gravity", &pb.CompressImageRequest{Gravity: "up"}},
+		{"background", &pb.CompressImageRequest{Background: "#12345"}},
+		{"kernel", &pb.CompressImageRequest{Kernel: "lanczos4"}},
+		{"png_compression", &pb.CompressImageRequest{PngCompression: "ultra"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := paramsFromRequest(tt.req); err == nil || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("%s: err = %v, want an error naming the field", tt.field, err)
+		}
+	}
+}
+
+func TestParamsFromRequestDefaults(t *testing.T) {
+	params, err := paramsFromRequest(&pb.CompressImageRequest{})
+	if err != nil {
+		t.Fatalf("paramsFromRequest(empty): %v", err)
+	}
+	if params.Metadata != "strip" {
+		t.Errorf("default metadata policy %q, want strip", params.Metadata)
+	}
+}
@@ -3,6 +3,8 @@ package metrics
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -40,7 +42,71 @@ var (
 			Help:    "Ratio of compressed image size to original image size",
 			Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0},
 		},
-		[]string{"format", "algorithm"},
+		[]string{"format", "algorithm", "kernel"},
+	)
+
+	resizeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "image_compression_resize_duration_seconds",
+			Help:    "Time taken to resample an image, by kernel",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"kernel"},
+	)
+
+	webpEncodes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_compression_webp_encodes_total",
+			Help: "Total number of WebP encodes, by mode and exact-alpha setting",
+		},
+		[]string{"mode", "exact"},
+	)
+
+	bestFormatChoices = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_compression_best_format_total",
+			Help: "Total number of times each format won a best-format comparison",
+		},
+		[]string{"format"},
+	)
+
+	queueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "image_compression_queue_depth",
+			Help: "Number of jobs waiting for a worker, by priority",
+		},
+		[]string{"priority"},
+	)
+
+	tenantQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "image_compression_tenant_queue_depth",
+			Help: "Number of jobs waiting for a worker, by tenant",
+		},
+		[]string{"tenant"},
+	)
+
+	memoryReserved = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "image_compression_memory_reserved_bytes",
+			Help: "Estimated memory reserved by running jobs against the memory budget",
+		},
+	)
+
+	jobsRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_compression_jobs_rejected_total",
+			Help: "Total number of jobs turned away because the worker pool was overloaded, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	jobsCancelled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_compression_jobs_cancelled_total",
+			Help: "Total number of jobs dropped because the caller gave up, by stage",
+		},
+		[]string{"stage"},
 	)
 
 	workerGauge = prometheus.NewGauge(
@@ -120,9 +186,33 @@ func Init() error {
 	if err := prometheus.Register(compressionRatio); err != nil {
 		return fmt.Errorf("failed to register compression ratio: %w", err)
 	}
+	if err := prometheus.Register(resizeDuration); err != nil {
+		return fmt.Errorf("failed to register resize duration: %w", err)
+	}
+	if err := prometheus.Register(webpEncodes); err != nil {
+		return fmt.Errorf("failed to register webp encodes: %w", err)
+	}
+	if err := prometheus.Register(bestFormatChoices); err != nil {
+		return fmt.Errorf("failed to register best format choices: %w", err)
+	}
 	if err := prometheus.Register(workerGauge); err != nil {
 		return fmt.Errorf("failed to register worker gauge: %w", err)
 	}
+	if err := prometheus.Register(queueDepth); err != nil {
+		return fmt.Errorf("failed to register queue depth: %w", err)
+	}
+	if err := prometheus.Register(tenantQueueDepth); err != nil {
+		return fmt.Errorf("failed to register tenant queue depth: %w", err)
+	}
+	if err := prometheus.Register(memoryReserved); err != nil {
+		return fmt.Errorf("failed to register reserved memory: %w", err)
+	}
+	if err := prometheus.Register(jobsRejected); err != nil {
+		return fmt.Errorf("failed to register rejected jobs: %w", err)
+	}
+	if err := prometheus.Register(jobsCancelled); err != nil {
+		return fmt.Errorf("failed to register cancelled jobs: %w", err)
+	}
 	
 	// Process resource metrics
 	if err := prometheus.Register(memoryUsage); err != nil {
@@ -194,10 +284,10 @@ func UpdateSystemCPUUsage(percentUsed float64) {
 // Performance metrics
 
 // RecordCompressionRatio records the compression ratio metric
-func RecordCompressionRatio(format, algorithm string, originalSize, compressedSize int) {
+func RecordCompressionRatio(format, algorithm, kernel string, originalSize, compressedSize int) {
 	if originalSize > 0 {
 		ratio := float64(compressedSize) / float64(originalSize)
-		compressionRatio.WithLabelValues(format, algorithm).Observe(ratio)
+		compressionRatio.WithLabelValues(format, algorithm, kernel).Observe(ratio)
 		
 		// Update throughput metrics
 		throughputImages.Inc()
@@ -205,6 +295,47 @@ func RecordCompressionRatio(format, algorithm string, originalSize, compressedSi
 	}
 }
 
+// RecordResizeDuration records how long a resample took with the given kernel
+func RecordResizeDuration(kernel string, duration time.Duration) {
+	resizeDuration.WithLabelValues(kernel).Observe(duration.Seconds())
+}
+
+// RecordWebPEncode counts a WebP encode in the given mode (lossy, lossless or near_lossless)
+func RecordWebPEncode(mode string, exact bool) {
+	webpEncodes.WithLabelValues(mode, strconv.FormatBool(exact)).Inc()
+}
+
+// RecordBestFormat counts the format picked by a best-format comparison
+func RecordBestFormat(format string) {
+	bestFormatChoices.WithLabelValues(format).Inc()
+}
+
+// RecordQueueDepth sets the number of jobs waiting for a worker at a priority
+func RecordQueueDepth(priority string, depth int) {
+	queueDepth.WithLabelValues(priority).Set(float64(depth))
+}
+
+// RecordTenantQueueDepth sets the number of jobs a tenant has waiting for a worker
+func RecordTenantQueueDepth(tenant string, depth int) {
+	tenantQueueDepth.WithLabelValues(tenant).Set(float64(depth))
+}
+
+// RecordMemoryReserved sets the estimated memory reserved by running jobs
+func RecordMemoryReserved(bytes int64) {
+	memoryReserved.Set(float64(bytes))
+}
+
+// RecordJobRejected counts a job turned away by admission control
+func RecordJobRejected(reason string) {
+	jobsRejected.WithLabelValues(reason).Inc()
+}
+
+// RecordJobCancelled counts a job dropped because its context ended while it
+// was being submitted, waiting in the queue or running
+func RecordJobCancelled(stage string) {
+	jobsCancelled.WithLabelValues(stage).Inc()
+}
+
 // Getter functions
 
 // GetRequestCounter returns the request counter metric
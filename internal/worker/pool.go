@@ -2,7 +2,11 @@
 package worker
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,11 +14,31 @@ import (
 	"github.com/teamleaderleo/potato-quality-image-compressor/internal/metrics"
 )
 
-// Job represents a generic worker job
+// ErrOverloaded is returned by Admit when the pool can't take a job in time
+var ErrOverloaded = errors.New("worker pool overloaded")
+
+const (
+	// avgSmoothing is the weight of the newest job in the running average job time
+	avgSmoothing = 8
+
+	// starvationInterval makes every nth pick of a worker start from the lowest
+	// priority, so lower classes keep moving under steady interactive load
+	starvationInterval = 8
+)
+
+// Job represents a generic worker job. Process should return early with the
+// context's error once ctx is done.
 type Job interface {
 	ID() string
 	
-	Process() (JobResult, error)
+	Process(ctx context.Context) (JobResult, error)
+}
+
+// MemoryEstimator is implemented by jobs that know how much memory they need.
+// The pool asks before queueing the job and runs it only once that much of
+// its memory budget is free; an error rejects the job.
+type MemoryEstimator interface {
+	EstimateMemory() (int64, error)
 }
 
 // JobResult represents the generic result of a job
@@ -22,25 +46,46 @@ type JobResult interface {
 	ID() string
 }
 
-// Pool manages a pool of worker goroutines for parallel processing
+// Pool manages a pool of worker goroutines for parallel processing. Jobs wait
+// in one queue per priority, shared fairly between tenants; slots holds a
+// token for every job waiting at a priority and bounds its queue.
 type Pool struct {
-	jobs           chan jobWrapper
+	mu             sync.Mutex
+	available      *sync.Cond // signalled when a job may have become runnable
+	queues         [priorityLevels]*fairQueue
+	slots          [priorityLevels]chan struct{}
+	tenants        map[string]TenantLimits
+	defaultLimits  TenantLimits
+	running        map[string]int // running jobs per tenant
+	memoryBudget   int64          // bytes running jobs may reserve, 0 for no limit
+	memoryReserved int64
+	queued         map[string]int // waiting jobs per tenant
+	enqueued       uint64         // jobs ever queued, numbering them in arrival order
+	closed         bool
 	workerCount    int
 	busyWorkers    int32 // atomic counter for metrics
 	wg             sync.WaitGroup
 	shuttingDown   bool
 	shutdownMutex  sync.Mutex
 	metricsEnabled bool
+	maxWait        time.Duration
+	avgJobTime     int64 // atomic running average in nanoseconds
 }
 
-// jobWrapper wraps a job with its result channel
+// jobWrapper wraps a job with its context, scheduling class and result channels
 type jobWrapper struct {
-	job    Job
-	result chan<- JobResult
-	err    chan<- error
+	ctx      context.Context
+	job      Job
+	tenant   string
+	priority Priority
+	memory   int64
+	seq      uint64 // arrival order, set when the job is queued
+	result   chan<- JobResult
+	err      chan<- error
 }
 
-// NewPool creates a new worker pool with the specified number of workers
+// NewPool creates a new worker pool with the specified number of workers and
+// room for jobQueueSize waiting jobs at each priority
 func NewPool(workerCount int, jobQueueSize int, enableMetrics bool) *Pool {
 	if workerCount <= 0 {
 		workerCount = 1
@@ -50,10 +95,18 @@ func NewPool(workerCount int, jobQueueSize int, enableMetrics bool) *Pool {
 	}
 
 	pool := &Pool{
-		jobs:           make(chan jobWrapper, jobQueueSize),
+		tenants:        make(map[string]TenantLimits),
+		defaultLimits:  TenantLimits{Weight: 1},
+		running:        make(map[string]int),
+		queued:         make(map[string]int),
 		workerCount:    workerCount,
 		metricsEnabled: enableMetrics,
 	}
+	pool.available = sync.NewCond(&pool.mu)
+	for i := range pool.queues {
+		pool.queues[i] = newFairQueue()
+		pool.slots[i] = make(chan struct{}, jobQueueSize)
+	}
 
 	// Start the workers
 	pool.wg.Add(workerCount)
@@ -68,36 +121,221 @@ func NewPool(workerCount int, jobQueueSize int, enableMetrics bool) *Pool {
 func (p *Pool) worker(id int) {
 	defer p.wg.Done()
 
-	for wrapper := range p.jobs {
+	for picks := 0; ; picks++ {
+		wrapper, ok := p.next(picks)
+		if !ok {
+			return
+		}
+		p.run(wrapper)
+		p.finish(wrapper)
+	}
+}
+
+// run processes one job and delivers its outcome
+func (p *Pool) run(wrapper jobWrapper) {
+	// Skip jobs whose caller gave up while they were queued
+	if err := wrapper.ctx.Err(); err != nil {
 		if p.metricsEnabled {
-			atomic.AddInt32(&p.busyWorkers, 1)
-			(*metrics.GetWorkerGauge()).Inc()
+			metrics.RecordJobCancelled("queued")
 		}
+		wrapper.err <- err
+		return
+	}
+
+	if p.metricsEnabled {
+		atomic.AddInt32(&p.busyWorkers, 1)
+		(*metrics.GetWorkerGauge()).Inc()
+	}
 
-		startTime := time.Now()
+	startTime := time.Now()
 
-		// Process the job
-		result, err := wrapper.job.Process()
-		
-		// Send the result
-		if err != nil {
-			wrapper.err <- err
-		} else {
-			wrapper.result <- result
+	// Process the job
+	result, err := wrapper.job.Process(wrapper.ctx)
+	if err != nil && p.metricsEnabled && wrapper.ctx.Err() != nil && isContextError(err) {
+		metrics.RecordJobCancelled("running")
+	}
+
+	// Send the result
+	if err != nil {
+		wrapper.err <- err
+	} else {
+		wrapper.result <- result
+	}
+
+	jobTime := time.Since(startTime)
+	p.observeJobTime(jobTime)
+
+	if p.metricsEnabled {
+		atomic.AddInt32(&p.busyWorkers, -1)
+		(*metrics.GetWorkerGauge()).Dec()
+		(*metrics.GetJobDuration()).Observe(jobTime.Seconds())
+	}
+}
+
+// next waits for the job a worker should run next and reports false once the
+// pool is shut down and drained
+func (p *Pool) next(picks int) (jobWrapper, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if wrapper, ok := p.take(picks); ok {
+			return wrapper, true
 		}
+		if p.closed && p.queuedJobs() == 0 {
+			return jobWrapper{}, false
+		}
+		p.available.Wait()
+	}
+}
 
-		jobTime := time.Since(startTime)
+// take removes the next runnable job, highest priority first except on every
+// starvationInterval-th pick. Within a priority tenants take turns by weight,
+// skipping tenants at their in-flight limit and jobs that don't fit in the
+// free memory budget, unless the job that has waited longest is the one that
+// doesn't fit. Callers hold p.mu.
+func (p *Pool) take(picks int) (jobWrapper, bool) {
+	if p.memoryBlocked() {
+		return jobWrapper{}, false
+	}
 
-		if p.metricsEnabled {
-			atomic.AddInt32(&p.busyWorkers, -1)
-			(*metrics.GetWorkerGauge()).Dec()
-			(*metrics.GetJobDuration()).Observe(jobTime.Seconds())
+	for i := 0; i < priorityLevels; i++ {
+		level := i
+		if picks%starvationInterval == starvationInterval-1 {
+			level = priorityLevels - 1 - i
+		}
+
+		wrapper, ok := p.queues[level].pop(p.canRun)
+		if !ok {
+			continue
+		}
+		<-p.slots[level]
+		p.running[wrapper.tenant]++
+		p.reserveMemory(wrapper.memory)
+		p.dequeued(wrapper)
+		return wrapper, true
+	}
+	return jobWrapper{}, false
+}
+
+// memoryBlocked reports whether the oldest job its tenant may start at the
+// highest priority with such a job is held back by the memory budget. Nothing
+// else starts until it fits, or a stream of smaller jobs could keep the budget
+// busy forever. Callers hold p.mu.
+func (p *Pool) memoryBlocked() bool {
+	for _, queue := range p.queues {
+		if oldest, ok := queue.oldest(p.belowInFlightLimit); ok {
+			return !p.fitsMemory(oldest)
+		}
+	}
+	return false
+}
+
+// canRun reports whether a job's tenant is below its in-flight limit and the
+// job fits in the free memory budget. Callers hold p.mu.
+func (p *Pool) canRun(wrapper jobWrapper) bool {
+	return p.belowInFlightLimit(wrapper) && p.fitsMemory(wrapper)
+}
+
+// belowInFlightLimit reports whether a job's tenant may start another job.
+// Callers hold p.mu.
+func (p *Pool) belowInFlightLimit(wrapper jobWrapper) bool {
+	limit := p.limits(wrapper.tenant).MaxInFlight
+	return limit <= 0 || p.running[wrapper.tenant] < limit
+}
+
+// fitsMemory reports whether a job fits in the free memory budget. A job
+// larger than the whole budget runs alone. Callers hold p.mu.
+func (p *Pool) fitsMemory(wrapper jobWrapper) bool {
+	return p.memoryBudget <= 0 || p.memoryReserved == 0 ||
+		p.memoryReserved+wrapper.memory <= p.memoryBudget
+}
+
+// finish releases a job's in-flight slot and memory, and wakes workers that
+// may have been waiting on them
+func (p *Pool) finish(wrapper jobWrapper) {
+	p.mu.Lock()
+	if p.running[wrapper.tenant]--; p.running[wrapper.tenant] <= 0 {
+		delete(p.running, wrapper.tenant)
+	}
+	p.reserveMemory(-wrapper.memory)
+	p.mu.Unlock()
+	p.available.Broadcast()
+}
+
+// reserveMemory adds to the memory reserved by running jobs. Callers hold p.mu.
+func (p *Pool) reserveMemory(bytes int64) {
+	p.memoryReserved += bytes
+	if p.metricsEnabled {
+		metrics.RecordMemoryReserved(p.memoryReserved)
+	}
+}
+
+// enqueue places a job that holds a queue slot and wakes a worker for it
+func (p *Pool) enqueue(wrapper jobWrapper) {
+	p.mu.Lock()
+	p.enqueued++
+	wrapper.seq = p.enqueued
+	p.queues[wrapper.priority].push(wrapper, p.limits(wrapper.tenant).Weight)
+	p.queued[wrapper.tenant]++
+	p.recordDepth(wrapper)
+	p.mu.Unlock()
+	p.available.Signal()
+}
+
+// dequeued updates the waiting counts for a job leaving the queue. Callers hold p.mu.
+func (p *Pool) dequeued(wrapper jobWrapper) {
+	if p.queued[wrapper.tenant]--; p.queued[wrapper.tenant] <= 0 {
+		delete(p.queued, wrapper.tenant)
+	}
+	p.recordDepth(wrapper)
+}
+
+// recordDepth exports the queue depths touched by a job. Callers hold p.mu.
+func (p *Pool) recordDepth(wrapper jobWrapper) {
+	if !p.metricsEnabled {
+		return
+	}
+	metrics.RecordQueueDepth(wrapper.priority.String(), p.queues[wrapper.priority].size)
+	metrics.RecordTenantQueueDepth(wrapper.tenant, p.queued[wrapper.tenant])
+}
+
+// queuedJobs returns the number of waiting jobs. Callers hold p.mu.
+func (p *Pool) queuedJobs() int {
+	total := 0
+	for _, queue := range p.queues {
+		total += queue.size
+	}
+	return total
+}
+
+// wrap builds the queue entry for a job, scheduled by the priority and tenant
+// on ctx and sized by the job's memory estimate
+func (p *Pool) wrap(ctx context.Context, job Job, resultChan chan<- JobResult, errChan chan<- error) (jobWrapper, error) {
+	var memory int64
+	if estimator, ok := job.(MemoryEstimator); ok {
+		var err error
+		if memory, err = estimator.EstimateMemory(); err != nil {
+			return jobWrapper{}, err
 		}
 	}
+
+	priority, _ := PriorityFromContext(ctx)
+	return jobWrapper{
+		ctx:      ctx,
+		job:      job,
+		tenant:   p.scheduledTenant(TenantFromContext(ctx)),
+		priority: priority,
+		memory:   memory,
+		result:   resultChan,
+		err:      errChan,
+	}, nil
 }
 
-// Submit adds a job to the worker pool
-func (p *Pool) Submit(job Job, resultChan chan<- JobResult, errChan chan<- error) error {
+// Submit adds a job to the worker pool, waiting for queue space until ctx is
+// done. The job runs with ctx, at the priority and for the tenant set by
+// WithPriority and WithTenant, and is skipped if ctx ends while it is queued.
+func (p *Pool) Submit(ctx context.Context, job Job, resultChan chan<- JobResult, errChan chan<- error) error {
 	p.shutdownMutex.Lock()
 	if p.shuttingDown {
 		p.shutdownMutex.Unlock()
@@ -105,13 +343,90 @@ func (p *Pool) Submit(job Job, resultChan chan<- JobResult, errChan chan<- error
 	}
 	p.shutdownMutex.Unlock()
 
-	p.jobs <- jobWrapper{
-		job:    job,
-		result: resultChan,
-		err:    errChan,
+	wrapper, err := p.wrap(ctx, job, resultChan, errChan)
+	if err != nil {
+		return err
+	}
+	select {
+	case p.slots[wrapper.priority] <- struct{}{}:
+		p.enqueue(wrapper)
+		return nil
+	case <-ctx.Done():
+		if p.metricsEnabled {
+			metrics.RecordJobCancelled("submit")
+		}
+		return ctx.Err()
+	}
+}
+
+// Admit adds a job without waiting for queue space. It fails with
+// ErrOverloaded when the queue or the tenant's share of it is full, or the
+// estimated wait for a worker exceeds the pool's maximum wait, so callers can
+// shed load instead of piling up.
+func (p *Pool) Admit(ctx context.Context, job Job, resultChan chan<- JobResult, errChan chan<- error) error {
+	p.shutdownMutex.Lock()
+	defer p.shutdownMutex.Unlock()
+	if p.shuttingDown {
+		return io.ErrClosedPipe
+	}
+
+	wrapper, err := p.wrap(ctx, job, resultChan, errChan)
+	if err != nil {
+		return err
+	}
+	if p.maxWait > 0 && p.waitAhead(wrapper.priority) > p.maxWait {
+		return p.reject("wait_too_long")
+	}
+	if p.tenantFull(wrapper.tenant) {
+		return p.reject("tenant_queue_full")
+	}
+
+	select {
+	case p.slots[wrapper.priority] <- struct{}{}:
+		p.enqueue(wrapper)
+		return nil
+	default:
+		return p.reject("queue_full")
+	}
+}
+
+// tenantFull reports whether tenant has as many waiting jobs as it may
+func (p *Pool) tenantFull(tenant string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	limit := p.limits(tenant).MaxQueued
+	return limit > 0 && p.queued[tenant] >= limit
+}
+
+// reject counts a job turned away by Admit and returns the error for it
+func (p *Pool) reject(reason string) error {
+	if p.metricsEnabled {
+		metrics.RecordJobRejected(reason)
+	}
+	return fmt.Errorf("%w: %s", ErrOverloaded, strings.ReplaceAll(reason, "_", " "))
+}
+
+// TrySubmit adds a job only if the queue has room, and reports whether it did.
+// Jobs that fan out work from inside a worker use it so they never block on a
+// queue that only they could drain.
+func (p *Pool) TrySubmit(ctx context.Context, job Job, resultChan chan<- JobResult, errChan chan<- error) bool {
+	p.shutdownMutex.Lock()
+	defer p.shutdownMutex.Unlock()
+	if p.shuttingDown {
+		return false
+	}
+
+	wrapper, err := p.wrap(ctx, job, resultChan, errChan)
+	if err != nil {
+		return false
+	}
+	select {
+	case p.slots[wrapper.priority] <- struct{}{}:
+		p.enqueue(wrapper)
+		return true
+	default:
+		return false
 	}
-	
-	return nil
 }
 
 // Shutdown gracefully shuts down the worker pool
@@ -124,10 +439,104 @@ func (p *Pool) Shutdown() {
 	p.shuttingDown = true
 	p.shutdownMutex.Unlock()
 
-	close(p.jobs)
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.available.Broadcast()
 	p.wg.Wait()
 }
 
+// SetTenants sets the limits of named tenants and of every other tenant.
+// Tenants without their own limits share one queue as DefaultTenant.
+func (p *Pool) SetTenants(tenants map[string]TenantLimits, defaults TenantLimits) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tenants = make(map[string]TenantLimits, len(tenants))
+	for name, limits := range tenants {
+		p.tenants[name] = limits
+	}
+	p.defaultLimits = defaults
+}
+
+// limits returns a tenant's limits. Callers hold p.mu.
+func (p *Pool) limits(tenant string) TenantLimits {
+	if limits, ok := p.tenants[tenant]; ok {
+		return limits
+	}
+	return p.defaultLimits
+}
+
+// scheduledTenant returns the tenant a job is queued under: its own when it
+// has configured limits, otherwise the shared DefaultTenant. Tenant names come
+// from clients, so made-up names must not each get a fair share of their own.
+func (p *Pool) scheduledTenant(tenant string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.tenants[tenant]; ok {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// SetMemoryBudget sets how many bytes, by their MemoryEstimator estimates,
+// the running jobs may need together; zero or less removes the limit
+func (p *Pool) SetMemoryBudget(bytes int64) {
+	p.mu.Lock()
+	p.memoryBudget = bytes
+	p.mu.Unlock()
+	p.available.Broadcast()
+}
+
+// SetMaxWait sets how long a job admitted by Admit may be expected to wait
+// for a worker; zero only rejects jobs when the queue is full
+func (p *Pool) SetMaxWait(maxWait time.Duration) {
+	p.maxWait = maxWait
+}
+
+// EstimatedWait estimates how long it takes the workers to drain every
+// queued job, from the queue depth and the running average job time
+func (p *Pool) EstimatedWait() time.Duration {
+	return p.waitAhead(PriorityBackground)
+}
+
+// waitAhead estimates how long a new job at priority would wait behind the
+// jobs queued at the same or a higher priority
+func (p *Pool) waitAhead(priority Priority) time.Duration {
+	ahead := 0
+	for level := PriorityInteractive; level <= priority; level++ {
+		ahead += len(p.slots[level])
+	}
+	avg := atomic.LoadInt64(&p.avgJobTime)
+	return time.Duration(avg * int64(ahead) / int64(p.workerCount))
+}
+
+// QueueDepth returns the number of jobs waiting for a worker
+func (p *Pool) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queuedJobs()
+}
+
+// observeJobTime folds a finished job's duration into the running average
+func (p *Pool) observeJobTime(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&p.avgJobTime)
+		avg := int64(d)
+		if old > 0 {
+			avg = old + (int64(d)-old)/avgSmoothing
+		}
+		if atomic.CompareAndSwapInt64(&p.avgJobTime, old, avg) {
+			return
+		}
+	}
+}
+
+// isContextError reports whether err comes from a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // BusyWorkerCount returns the number of busy workers
 func (p *Pool) BusyWorkerCount() int {
 	return int(atomic.LoadInt32(&p.busyWorkers))
@@ -136,4 +545,4 @@ func (p *Pool) BusyWorkerCount() int {
 // TotalWorkerCount returns the total number of workers
 func (p *Pool) TotalWorkerCount() int {
 	return p.workerCount
-}
\ No newline at end of file
+}
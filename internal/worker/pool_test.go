@@ -0,0 +1,321 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingJob runs until release is closed
+type blockingJob struct {
+	id      string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingJob(id string) *blockingJob {
+	return &blockingJob{id: id, started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (j *blockingJob) ID() string { return j.id }
+
+// finish lets the job return, and may be called more than once
+func (j *blockingJob) finish() { j.once.Do(func() { close(j.release) }) }
+
+func (j *blockingJob) Process(ctx context.Context) (JobResult, error) {
+	close(j.started)
+	select {
+	case <-j.release:
+		return j, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// occupy admits a job and waits until a worker has picked it up
+func occupy(t *testing.T, pool *Pool, job *blockingJob) {
+	t.Helper()
+	if err := pool.Admit(context.Background(), job, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(%s): %v", job.id, err)
+	}
+	select {
+	case <-job.started:
+	case <-time.After(time.Second):
+		t.Fatalf("%s never started", job.id)
+	}
+}
+
+func TestAdmitShedsWhenQueueFull(t *testing.T) {
+	pool := NewPool(1, 1, false)
+	defer pool.Shutdown()
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer close(running.release)
+
+	// The single queue slot takes one job, the next one is shed
+	queued := newBlockingJob("queued")
+	defer close(queued.release)
+	if err := pool.Admit(context.Background(), queued, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(queued): %v", err)
+	}
+
+	err := pool.Admit(context.Background(), newBlockingJob("shed"), make(chan JobResult, 1), make(chan error, 1))
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Admit on a full queue: err = %v, want ErrOverloaded", err)
+	}
+}
+
+func TestAdmitShedsTenantOverItsShare(t *testing.T) {
+	pool := NewPool(1, 4, false)
+	defer pool.Shutdown()
+	pool.SetTenants(map[string]TenantLimits{"small": {Weight: 1, MaxQueued: 1}}, TenantLimits{Weight: 1})
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer close(running.release)
+
+	ctx := WithTenant(context.Background(), "small")
+	first := newBlockingJob("first")
+	defer close(first.release)
+	if err := pool.Admit(ctx, first, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(first): %v", err)
+	}
+
+	err := pool.Admit(ctx, newBlockingJob("second"), make(chan JobResult, 1), make(chan error, 1))
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Admit over the tenant limit: err = %v, want ErrOverloaded", err)
+	}
+
+	// Other tenants still have room
+	other := newBlockingJob("other")
+	defer close(other.release)
+	if err := pool.Admit(context.Background(), other, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Errorf("Admit for another tenant: %v", err)
+	}
+}
+
+func TestAdmitShedsOnLongWait(t *testing.T) {
+	pool := NewPool(1, 8, false)
+	defer pool.Shutdown()
+	pool.SetMaxWait(time.Second)
+	pool.observeJobTime(10 * time.Second)
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer close(running.release)
+
+	// Nothing waits yet, so the first job is admitted
+	queued := newBlockingJob("queued")
+	defer close(queued.release)
+	if err := pool.Admit(context.Background(), queued, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(queued): %v", err)
+	}
+
+	// One 10s job ahead is over the 1s maximum wait
+	err := pool.Admit(context.Background(), newBlockingJob("shed"), make(chan JobResult, 1), make(chan error, 1))
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Admit behind a long wait: err = %v, want ErrOverloaded", err)
+	}
+}
+
+// memoryJob is a blockingJob with a memory estimate
+type memoryJob struct {
+	*blockingJob
+	memory int64
+}
+
+func (j memoryJob) EstimateMemory() (int64, error) { return j.memory, nil }
+
+// started reports whether job has started within wait
+func started(job *blockingJob, wait time.Duration) bool {
+	select {
+	case <-job.started:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+func TestLargeJobHoldsBackSmallerOnes(t *testing.T) {
+	pool := NewPool(2, 8, false)
+	defer pool.Shutdown()
+	pool.SetMemoryBudget(100)
+
+	running := newBlockingJob("running")
+	defer running.finish()
+	if err := pool.Admit(context.Background(), memoryJob{running, 60}, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(running): %v", err)
+	}
+	if !started(running, time.Second) {
+		t.Fatal("running never started")
+	}
+
+	// The large job doesn't fit next to the running one; the small one would
+	large, small := newBlockingJob("large"), newBlockingJob("small")
+	defer close(large.release)
+	defer close(small.release)
+	if err := pool.Admit(context.Background(), memoryJob{large, 60}, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(large): %v", err)
+	}
+	batch := WithPriority(context.Background(), PriorityBatch)
+	if err := pool.Admit(batch, memoryJob{small, 10}, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(small): %v", err)
+	}
+
+	if started(small, 50*time.Millisecond) {
+		t.Fatal("a smaller job jumped ahead of the large job waiting for memory")
+	}
+
+	running.finish()
+	if !started(large, time.Second) {
+		t.Fatal("large job never started once memory was free")
+	}
+	if !started(small, time.Second) {
+		t.Error("small job never started once the large job fit")
+	}
+}
+
+func TestCancelledQueuedJobIsSkipped(t *testing.T) {
+	pool := NewPool(1, 4, false)
+	defer pool.Shutdown()
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer running.finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := newBlockingJob("queued")
+	defer queued.finish()
+	errChan := make(chan error, 1)
+	if err := pool.Submit(ctx, queued, make(chan JobResult, 1), errChan); err != nil {
+		t.Fatalf("Submit(queued): %v", err)
+	}
+
+	cancel()
+	running.finish()
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("queued job failed with %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled job never reported an error")
+	}
+	if started(queued, 10*time.Millisecond) {
+		t.Error("cancelled job was processed")
+	}
+}
+
+func TestSubmitGivesUpWhenQueueStaysFull(t *testing.T) {
+	pool := NewPool(1, 1, false)
+	defer pool.Shutdown()
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer running.finish()
+
+	queued := newBlockingJob("queued")
+	defer queued.finish()
+	if err := pool.Submit(context.Background(), queued, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Submit(queued): %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := pool.Submit(ctx, newBlockingJob("waiting"), make(chan JobResult, 1), make(chan error, 1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit on a full queue: err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// orderJob records the order jobs start in
+type orderJob struct {
+	id    string
+	mu    *sync.Mutex
+	order *[]string
+}
+
+func (j orderJob) ID() string { return j.id }
+
+func (j orderJob) Process(ctx context.Context) (JobResult, error) {
+	j.mu.Lock()
+	*j.order = append(*j.order, j.id)
+	j.mu.Unlock()
+	return j, nil
+}
+
+// runInOrder queues the jobs at their priorities behind a running job, lets
+// them all run and returns the order they started in
+func runInOrder(t *testing.T, pool *Pool, ids []string, priorities []Priority) []string {
+	t.Helper()
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer running.finish()
+
+	var mu sync.Mutex
+	var order []string
+	results := make(chan JobResult, len(ids))
+	for i, id := range ids {
+		ctx := WithPriority(context.Background(), priorities[i])
+		if err := pool.Submit(ctx, orderJob{id, &mu, &order}, results, make(chan error, 1)); err != nil {
+			t.Fatalf("Submit(%s): %v", id, err)
+		}
+	}
+
+	running.finish()
+	for range ids {
+		select {
+		case <-results:
+		case <-time.After(time.Second):
+			t.Fatal("queued jobs never finished")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return order
+}
+
+func TestInteractiveJobsRunBeforeBatch(t *testing.T) {
+	pool := NewPool(1, 8, false)
+	defer pool.Shutdown()
+
+	// Batch jobs are queued first, interactive ones still overtake them
+	ids := []string{"batch1", "batch2", "batch3", "interactive1", "interactive2", "interactive3"}
+	priorities := []Priority{PriorityBatch, PriorityBatch, PriorityBatch, PriorityInteractive, PriorityInteractive, PriorityInteractive}
+	order := runInOrder(t, pool, ids, priorities)
+
+	want := []string{"interactive1", "interactive2", "interactive3", "batch1", "batch2", "batch3"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("jobs ran in order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestBackgroundJobRunsUnderInteractiveLoad(t *testing.T) {
+	load := 2 * starvationInterval
+	pool := NewPool(1, load, false)
+	defer pool.Shutdown()
+
+	ids := []string{"background"}
+	priorities := []Priority{PriorityBackground}
+	for i := 0; i < load; i++ {
+		ids = append(ids, fmt.Sprintf("interactive%d", i))
+		priorities = append(priorities, PriorityInteractive)
+	}
+	order := runInOrder(t, pool, ids, priorities)
+
+	// The running job was the worker's first pick
+	position := slices.Index(order, "background")
+	if position < 0 || position+1 >= starvationInterval {
+		t.Errorf("background job ran at pick %d, want within %d picks; order %v", position+1, starvationInterval, order)
+	}
+}
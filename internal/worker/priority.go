@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Priority orders jobs waiting for a worker, lower values run first
+type Priority int
+
+const (
+	// PriorityInteractive is for single requests a user is waiting on
+	PriorityInteractive Priority = iota
+	// PriorityBatch is for items of a batch request
+	PriorityBatch
+	// PriorityBackground is for work nobody is waiting on
+	PriorityBackground
+
+	// priorityLevels is the number of priority classes
+	priorityLevels = int(PriorityBackground) + 1
+)
+
+// ParsePriority parses a priority name, empty selects interactive
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(s) {
+	case "", "interactive":
+		return PriorityInteractive, nil
+	case "batch":
+		return PriorityBatch, nil
+	case "background":
+		return PriorityBackground, nil
+	default:
+		return PriorityInteractive, fmt.Errorf("unknown priority: %s", s)
+	}
+}
+
+// String returns the priority name
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return "batch"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "interactive"
+	}
+}
+
+// priorityKey is the context key for a job's priority
+type priorityKey struct{}
+
+// WithPriority returns a context whose jobs are queued at priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set on ctx, and whether one was set
+func PriorityFromContext(ctx context.Context) (Priority, bool) {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok || priority < 0 || int(priority) >= priorityLevels {
+		return PriorityInteractive, false
+	}
+	return priority, true
+}
@@ -0,0 +1,126 @@
+package worker
+
+import "context"
+
+// DefaultTenant owns jobs submitted without a tenant or for a tenant without
+// its own limits
+const DefaultTenant = "default"
+
+// TenantLimits are a tenant's share of the pool
+type TenantLimits struct {
+	// Weight is the tenant's share of workers relative to other tenants, at least 1
+	Weight int
+
+	// MaxInFlight caps the tenant's running jobs, 0 for no limit
+	MaxInFlight int
+
+	// MaxQueued caps the tenant's waiting jobs, 0 for no limit beyond the queue size
+	MaxQueued int
+}
+
+// tenantKey is the context key for a job's tenant
+type tenantKey struct{}
+
+// WithTenant returns a context whose jobs are scheduled for tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set on ctx, or DefaultTenant
+func TenantFromContext(ctx context.Context) string {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok || tenant == "" {
+		return DefaultTenant
+	}
+	return tenant
+}
+
+// tenantQueue holds one tenant's waiting jobs within a fairQueue
+type tenantQueue struct {
+	name    string
+	weight  int
+	deficit int
+	jobs    []jobWrapper
+}
+
+// fairQueue shares one priority class between tenants by deficit round-robin.
+// Every job costs one unit and each turn grants a tenant its weight in units,
+// so tenants with waiting jobs get workers in proportion to their weights.
+type fairQueue struct {
+	tenants map[string]*tenantQueue
+	active  []*tenantQueue // tenants with waiting jobs, in round-robin order
+	next    int
+	size    int
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{tenants: make(map[string]*tenantQueue)}
+}
+
+// push appends a job to its tenant's queue
+func (q *fairQueue) push(wrapper jobWrapper, weight int) {
+	t, exists := q.tenants[wrapper.tenant]
+	if !exists {
+		t = &tenantQueue{name: wrapper.tenant}
+		q.tenants[wrapper.tenant] = t
+		q.active = append(q.active, t)
+	}
+	t.weight = max(weight, 1)
+	t.jobs = append(t.jobs, wrapper)
+	q.size++
+}
+
+// pop removes the next job of the tenant whose turn it is, skipping tenants
+// whose oldest job isn't eligible to run right now
+func (q *fairQueue) pop(eligible func(wrapper jobWrapper) bool) (jobWrapper, bool) {
+	for tries := 0; tries < len(q.active); tries++ {
+		t := q.active[q.next]
+		if !eligible(t.jobs[0]) {
+			// A blocked tenant gives up the rest of its turn
+			t.deficit = 0
+			q.next = (q.next + 1) % len(q.active)
+			continue
+		}
+
+		if t.deficit == 0 {
+			t.deficit = t.weight
+		}
+		wrapper := t.jobs[0]
+		t.jobs[0] = jobWrapper{}
+		t.jobs = t.jobs[1:]
+		t.deficit--
+		q.size--
+
+		switch {
+		case len(t.jobs) == 0:
+			q.remove(q.next)
+		case t.deficit == 0:
+			q.next = (q.next + 1) % len(q.active)
+		}
+		return wrapper, true
+	}
+	return jobWrapper{}, false
+}
+
+// oldest returns the longest-waiting job among the tenants' next jobs,
+// skipping tenants whose next job isn't eligible
+func (q *fairQueue) oldest(eligible func(wrapper jobWrapper) bool) (jobWrapper, bool) {
+	var oldest jobWrapper
+	found := false
+	for _, t := range q.active {
+		if head := t.jobs[0]; eligible(head) && (!found || head.seq < oldest.seq) {
+			oldest, found = head, true
+		}
+	}
+	return oldest, found
+}
+
+// remove drops the tenant at index i from the round-robin, the next tenant
+// taking its turn
+func (q *fairQueue) remove(i int) {
+	delete(q.tenants, q.active[i].name)
+	q.active = append(q.active[:i], q.active[i+1:]...)
+	if q.next >= len(q.active) {
+		q.next = 0
+	}
+}
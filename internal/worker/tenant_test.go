@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"testing"
+)
+
+// always lets every job run
+func always(jobWrapper) bool { return true }
+
+func TestFairQueueSharesByWeight(t *testing.T) {
+	q := newFairQueue()
+	for i := 0; i < 30; i++ {
+		q.push(jobWrapper{tenant: "heavy"}, 3)
+		q.push(jobWrapper{tenant: "light"}, 1)
+	}
+
+	// While both have jobs waiting, heavy gets three turns for each of light's
+	counts := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		wrapper, ok := q.pop(always)
+		if !ok {
+			t.Fatalf("pop %d found no job", i)
+		}
+		counts[wrapper.tenant]++
+	}
+	if counts["heavy"] != 30 || counts["light"] != 10 {
+		t.Errorf("popped %v, want heavy 30 and light 10", counts)
+	}
+
+	// Once heavy is drained light takes every turn
+	for i := 0; i < 20; i++ {
+		wrapper, _ := q.pop(always)
+		if wrapper.tenant != "light" {
+			t.Fatalf("pop after drain went to %q", wrapper.tenant)
+		}
+	}
+	if _, ok := q.pop(always); ok || q.size != 0 {
+		t.Errorf("queue not empty, size %d", q.size)
+	}
+}
+
+func TestFairQueueSkipsBlockedTenants(t *testing.T) {
+	q := newFairQueue()
+	q.push(jobWrapper{tenant: "blocked"}, 5)
+	q.push(jobWrapper{tenant: "open"}, 1)
+	q.push(jobWrapper{tenant: "open"}, 1)
+
+	notBlocked := func(wrapper jobWrapper) bool { return wrapper.tenant != "blocked" }
+	for i := 0; i < 2; i++ {
+		wrapper, ok := q.pop(notBlocked)
+		if !ok || wrapper.tenant != "open" {
+			t.Fatalf("pop %d = %q, %v; want open", i, wrapper.tenant, ok)
+		}
+	}
+	if _, ok := q.pop(notBlocked); ok {
+		t.Error("popped a blocked tenant's job")
+	}
+	if wrapper, ok := q.pop(always); !ok || wrapper.tenant != "blocked" {
+		t.Errorf("blocked tenant's job lost once unblocked")
+	}
+}
+
+func TestUnconfiguredTenantsShareTheDefaultQueue(t *testing.T) {
+	pool := NewPool(1, 8, false)
+	defer pool.Shutdown()
+	pool.SetTenants(map[string]TenantLimits{"acme": {Weight: 2}}, TenantLimits{Weight: 1, MaxQueued: 1})
+
+	for _, tenant := range []string{"acme", "made-up-1", "made-up-2", ""} {
+		want := tenant
+		if tenant != "acme" {
+			want = DefaultTenant
+		}
+		if got := pool.scheduledTenant(TenantFromContext(WithTenant(context.Background(), tenant))); got != want {
+			t.Errorf("tenant %q scheduled as %q, want %q", tenant, got, want)
+		}
+	}
+
+	running := newBlockingJob("running")
+	occupy(t, pool, running)
+	defer close(running.release)
+
+	// Rotating names doesn't get around the default tenant's queue limit
+	first := newBlockingJob("first")
+	defer close(first.release)
+	if err := pool.Admit(WithTenant(context.Background(), "made-up-1"), first, make(chan JobResult, 1), make(chan error, 1)); err != nil {
+		t.Fatalf("Admit(first): %v", err)
+	}
+	if err := pool.Admit(WithTenant(context.Background(), "made-up-2"), newBlockingJob("second"), make(chan JobResult, 1), make(chan error, 1)); err == nil {
+		t.Error("a second made-up tenant got past the shared queue limit")
+	}
+}